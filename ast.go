@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
+	"github.com/laher/markdownfmt/markdown"
 	"github.com/russross/blackfriday/v2"
 )
 
@@ -12,16 +15,16 @@ func printAST(w io.Writer, node *blackfriday.Node) {
 	indent := 0
 	f := func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
 		if entering {
-			fmt.Print("\n")
+			fmt.Fprint(w, "\n")
 			for p := node.Parent; p != nil; p = p.Parent {
-				fmt.Print(" ")
+				fmt.Fprint(w, " ")
 			}
 			//fmt.Print(strings.Repeat(" ", indent))
 			//if node.Prev != nil {
 			//	fmt.Print(",")
 			//}
 
-			fmt.Printf("%v%d: [%v]", node.Type, node.Level, string(node.Literal))
+			fmt.Fprintf(w, "%v%d: [%v]", node.Type, node.Level, string(node.Literal))
 
 			if node.FirstChild != nil {
 				indent += 1
@@ -35,7 +38,7 @@ func printAST(w io.Writer, node *blackfriday.Node) {
 		return blackfriday.GoToNext
 	}
 	node.Walk(f)
-	fmt.Println()
+	fmt.Fprintln(w)
 }
 
 func paraNode(parent *blackfriday.Node) *blackfriday.Node {
@@ -48,6 +51,21 @@ func paraNode(parent *blackfriday.Node) *blackfriday.Node {
 	return p
 }
 
+// textParaNode adds a paragraph of plain text
+func textParaNode(parent *blackfriday.Node, text string) *blackfriday.Node {
+	p := paraNode(parent)
+	t := blackfriday.NewNode(blackfriday.Text)
+	t.Literal = []byte(text)
+	p.AppendChild(t)
+	return p
+}
+
+func textNode(text string) *blackfriday.Node {
+	n := blackfriday.NewNode(blackfriday.Text)
+	n.Literal = []byte(text)
+	return n
+}
+
 func headingNode(parent *blackfriday.Node, level int, text string) *blackfriday.Node {
 	h := blackfriday.NewNode(blackfriday.Heading)
 	h.Level = level
@@ -62,3 +80,86 @@ func todayNode(parent *blackfriday.Node) *blackfriday.Node {
 	t := time.Now()
 	return headingNode(parent, 1, fmt.Sprintf("%s", t.Format("2006-01-02, Monday")))
 }
+
+func newListNode() *blackfriday.Node {
+	l := blackfriday.NewNode(blackfriday.List)
+	l.ListData.Tight = true
+	l.ListData.BulletChar = '-'
+	return l
+}
+
+func listNode(parent *blackfriday.Node) *blackfriday.Node {
+	l := newListNode()
+	parent.AppendChild(l)
+	return l
+}
+
+func itemNode(list *blackfriday.Node, text string) *blackfriday.Node {
+	i := blackfriday.NewNode(blackfriday.Item)
+	i.ListData.BulletChar = '-'
+	list.AppendChild(i)
+	p := paraNode(i)
+	textNode := blackfriday.NewNode(blackfriday.Text)
+	textNode.Literal = []byte(text)
+	p.AppendChild(textNode)
+	return i
+}
+
+// inlineText renders the children of a node (e.g. a paragraph or heading) back to markdown, on one line
+func inlineText(node *blackfriday.Node) string {
+	var buf bytes.Buffer
+	r := markdown.NewRenderer(&markdown.Options{Terminal: false, HashHeaders: true})
+	for n := node.FirstChild; n != nil; n = n.Next {
+		n.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+			return r.RenderNode(&buf, node, entering)
+		})
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// cloneNode makes a deep copy of a node, detached from any parent
+func cloneNode(n *blackfriday.Node) *blackfriday.Node {
+	c := *n
+	c.Parent, c.FirstChild, c.LastChild, c.Prev, c.Next = nil, nil, nil, nil, nil
+	c.Literal = append([]byte{}, n.Literal...)
+	for child := n.FirstChild; child != nil; child = child.Next {
+		c.AppendChild(cloneNode(child))
+	}
+	return &c
+}
+
+// appendText adds text to the end of an item's first paragraph
+func appendText(item *blackfriday.Node, text string) {
+	p := item.FirstChild
+	if p == nil || p.Type != blackfriday.Paragraph {
+		return
+	}
+	last := p.LastChild
+	if last == nil || last.Type != blackfriday.Text {
+		last = blackfriday.NewNode(blackfriday.Text)
+		p.AppendChild(last)
+	}
+	last.Literal = append(bytes.TrimRight(last.Literal, " "), text...)
+}
+
+// filterItems unlinks items from the lists among nodes, unless keep returns true. Emptied lists are dropped
+func filterItems(nodes []*blackfriday.Node, keep func(item *blackfriday.Node) bool) []*blackfriday.Node {
+	ret := []*blackfriday.Node{}
+	for _, n := range nodes {
+		if n.Type != blackfriday.List {
+			ret = append(ret, n)
+			continue
+		}
+		for item := n.FirstChild; item != nil; {
+			next := item.Next
+			if !keep(item) {
+				item.Unlink()
+			}
+			item = next
+		}
+		if n.FirstChild != nil {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// rollover policies for custom sections
+const (
+	policyRolledOver = "rolled-over" // undone items move into Rolled Over
+	policySelf       = "self"        // undone items stay in the section
+	policyKeep       = "keep"        // the section is copied verbatim, e.g. for notes
+	policyDiscard    = "discard"     // the section starts empty each day
+	policyTemplate   = "template"    // the section is reset to its template each day
+)
+
+// builtinSections are always present, and their rollover behaviour is fixed
+var builtinSections = []string{"Inbox", "Rolled Over", "Daily"}
+
+// config is read from config.json in the base directory. Everything is optional
+type config struct {
+	Sections []sectionConfig `json:"sections,omitempty"`
+	Team     []teammate      `json:"team,omitempty"`
+	Standup  standupConfig   `json:"standup,omitempty"`
+	Rollover rolloverConfig  `json:"rollover,omitempty"`
+}
+
+type rolloverConfig struct {
+	Sort string `json:"sort,omitempty"` // "priority" sorts the Inbox and Rolled Over, by priority then age (from created:), otherwise keeping their order
+}
+
+type standupConfig struct {
+	Format string `json:"format,omitempty"` // the default for `today standup`
+}
+
+// teammate is someone's today directory, e.g. in a shared checkout. Relative directories are relative to the base directory
+type teammate struct {
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+}
+
+// sectionConfig is a section of today.md. The built in sections may be listed too, to position the others around them
+type sectionConfig struct {
+	Name     string `json:"name"`
+	Rollover string `json:"rollover,omitempty"`
+	Template string `json:"template,omitempty"` // markdown, for the template policy
+}
+
+func loadConfig() (config, error) {
+	cfg := config{}
+	file, err := getConfigFilename()
+	if err != nil {
+		return cfg, err
+	}
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %v", configBase, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %v", configBase, err)
+	}
+	return cfg, nil
+}
+
+func (c config) validate() error {
+	if f := c.Standup.Format; f != "" && !validStandupFormat(f) {
+		return fmt.Errorf("unknown standup format '%s'", f)
+	}
+	if s := c.Rollover.Sort; s != "" && s != sortPriority {
+		return fmt.Errorf("unknown rollover sort '%s' (expected %s)", s, sortPriority)
+	}
+	for _, t := range c.Team {
+		if t.Name == "" || t.Dir == "" {
+			return fmt.Errorf("team members need a name and a dir")
+		}
+	}
+	seen := map[string]bool{}
+	for _, s := range c.Sections {
+		if s.Name == "" {
+			return fmt.Errorf("section without a name")
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("section '%s' is listed twice", s.Name)
+		}
+		seen[s.Name] = true
+		if isBuiltinSection(s.Name) || s.Name == "Overdue" {
+			if s.Rollover != "" || s.Template != "" {
+				return fmt.Errorf("section '%s' is built in, so its rollover cannot be configured", s.Name)
+			}
+			continue
+		}
+		switch s.policy() {
+		case policyRolledOver, policySelf, policyKeep, policyDiscard:
+		case policyTemplate:
+			if s.Template == "" {
+				return fmt.Errorf("section '%s' has the template policy, but no template", s.Name)
+			}
+		default:
+			return fmt.Errorf("section '%s' has unknown rollover '%s' (expected %s, %s, %s, %s or %s)", s.Name, s.Rollover,
+				policyRolledOver, policySelf, policyKeep, policyDiscard, policyTemplate)
+		}
+	}
+	return nil
+}
+
+// layout is the sections of today.md, in order. Built in sections which aren't configured go in their usual place
+func (c config) layout() []sectionConfig {
+	layout := []sectionConfig{}
+	for _, s := range c.Sections {
+		if s.Name != "Overdue" { // always first, when there is anything overdue
+			layout = append(layout, s)
+		}
+	}
+	for i, b := range builtinSections {
+		if c.section(b) != nil {
+			continue
+		}
+		// after the previous built in section, or at the start
+		at := 0
+		for j := i - 1; j >= 0 && at == 0; j-- {
+			for k, s := range layout {
+				if s.Name == builtinSections[j] {
+					at = k + 1
+					break
+				}
+			}
+		}
+		layout = append(layout[:at], append([]sectionConfig{{Name: b}}, layout[at:]...)...)
+	}
+	return layout
+}
+
+func (c config) section(name string) *sectionConfig {
+	for i := range c.Sections {
+		if c.Sections[i].Name == name {
+			return &c.Sections[i]
+		}
+	}
+	return nil
+}
+
+// sectionNames are all the sections which rollover knows about
+func (c config) sectionNames() []string {
+	names := []string{"Overdue"}
+	for _, s := range c.layout() {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func (s sectionConfig) policy() string {
+	if s.Rollover == "" {
+		return policySelf
+	}
+	return s.Rollover
+}
+
+func isBuiltinSection(name string) bool {
+	for _, b := range builtinSections {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// templateNodes are fresh copies of the section's template
+func (s sectionConfig) templateNodes() ([]*blackfriday.Node, error) {
+	t, err := parse([]byte(s.Template))
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*blackfriday.Node{}
+	for n := t.node.FirstChild; n != nil; n = n.Next {
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+const (
+	dueEmoji       = "📅"
+	scheduledEmoji = "⏳"
+)
+
+// dateTokenRegexp matches inline date metadata with a key, e.g. `due:fri` or `scheduled:2026-10-20`
+var dateTokenRegexp = regexp.MustCompile(`\b(due|scheduled|created|completed):(\S+)`)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// day truncates to midnight, local time
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// parseDate accepts yyyy-mm-dd, 'today', 'tomorrow', a weekday name (the next one after ref),
+// or an offset such as +3d or +2w
+func parseDate(s string, ref time.Time) (time.Time, error) {
+	ref = day(ref)
+	s = strings.ToLower(strings.TrimRight(s, ".,;:!?)"))
+	switch s {
+	case "today":
+		return ref, nil
+	case "tomorrow":
+		return ref.AddDate(0, 0, 1), nil
+	}
+	if wd, ok := weekdayNames[s]; ok {
+		diff := (int(wd) - int(ref.Weekday()) + 7) % 7
+		if diff == 0 {
+			diff = 7
+		}
+		return ref.AddDate(0, 0, diff), nil
+	}
+	if len(s) > 2 && s[0] == '+' {
+		n, err := strconv.Atoi(s[1 : len(s)-1])
+		if err == nil {
+			switch s[len(s)-1] {
+			case 'd':
+				return ref.AddDate(0, 0, n), nil
+			case 'w':
+				return ref.AddDate(0, 0, 7*n), nil
+			case 'm':
+				return ref.AddDate(0, n, 0), nil
+			}
+		}
+	}
+	d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is not a valid date", s)
+	}
+	return d, nil
+}
+
+// headingDate is the date in the document's first heading, if any
+func (t tasks) headingDate() (time.Time, bool) {
+	h := t.GetFirstHeadingText()
+	if len(h) < 10 {
+		return time.Time{}, false
+	}
+	d, err := time.ParseInLocation("2006-01-02", h[:10], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return d, true
+}
+
+// normaliseDates rewrites relative dates in an item (e.g. `scheduled:fri`) as yyyy-mm-dd,
+// so that they keep their meaning once the item is moved to another day or file
+func normaliseDates(item *blackfriday.Node, ref time.Time) {
+	p := item.FirstChild
+	if p == nil || p.Type != blackfriday.Paragraph {
+		return
+	}
+	for n := p.FirstChild; n != nil; n = n.Next {
+		if n.Type != blackfriday.Text {
+			continue
+		}
+		n.Literal = dateTokenRegexp.ReplaceAllFunc(n.Literal, func(m []byte) []byte {
+			parts := dateTokenRegexp.FindSubmatch(m)
+			d, err := parseDate(string(parts[2]), ref)
+			if err != nil {
+				return m
+			}
+			return []byte(string(parts[1]) + ":" + d.Format("2006-01-02"))
+		})
+	}
+}
+
+// scheduledRegexp and dueRegexp match both forms of each date
+var (
+	scheduledRegexp = regexp.MustCompile(`\s*(\bscheduled:|` + scheduledEmoji + `\s*)\S+`)
+	dueRegexp       = regexp.MustCompile(`\s*(\bdue:|` + dueEmoji + `\s*)\S+`)
+)
+
+// setScheduled replaces any scheduled date in the item's text. A zero time just removes it
+func setScheduled(item *blackfriday.Node, d time.Time) {
+	p := item.FirstChild
+	if p == nil || p.Type != blackfriday.Paragraph {
+		return
+	}
+	for n := p.FirstChild; n != nil; n = n.Next {
+		if n.Type == blackfriday.Text {
+			n.Literal = scheduledRegexp.ReplaceAll(n.Literal, nil)
+		}
+	}
+	if !d.IsZero() {
+		appendText(item, " scheduled:"+d.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// deferTask moves a task out of today.md into later.md, either scheduled for a given day or for 'someday'
+func deferTask(args []string) error {
+	if len(args) < 3 {
+		return errors.New("usage: today defer <task> <date|someday>")
+	}
+	sel, when := strings.Join(args[1:len(args)-1], " "), args[len(args)-1]
+	var (
+		scheduled time.Time
+		err       error
+	)
+	if when != "someday" {
+		scheduled, err = parseDate(when, time.Now())
+		if err != nil {
+			return err
+		}
+		if !scheduled.After(day(time.Now())) {
+			return fmt.Errorf("%s is not in the future", scheduled.Format("2006-01-02"))
+		}
+	}
+	today, err := loadToday()
+	if err != nil {
+		return err
+	}
+	later, err := loadLater()
+	if err != nil {
+		return err
+	}
+	t, err := selectTask(flatten(today.Tasks()), sel)
+	if err != nil {
+		return err
+	}
+	list := t.node.Parent
+	t.node.Unlink()
+	if list.FirstChild == nil {
+		list.Unlink()
+	}
+	ref, ok := today.headingDate()
+	if !ok {
+		ref = time.Now()
+	}
+	normaliseDates(t.node, ref)
+	setScheduled(t.node, scheduled)
+	section := laterScheduled
+	if scheduled.IsZero() {
+		section = laterSomeday
+	}
+	later.SectionList(section).AppendChild(t.node)
+	if err := newLater(later); err != nil {
+		return err
+	}
+	f, err := getTodayFilename()
+	if err != nil {
+		return err
+	}
+	if err := newFile(f, today); err != nil {
+		return err
+	}
+	if scheduled.IsZero() {
+		fmt.Printf("Deferred '%s' until someday\n", t.Description)
+	} else {
+		fmt.Printf("Deferred '%s' until %s\n", t.Description, scheduled.Format("2006-01-02, Monday"))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+type logLevel int
+
+const (
+	levelQuiet logLevel = iota
+	levelVerbose
+	levelDebug
+)
+
+var (
+	// verbosity is set by the --verbose and --debug flags. Diagnostics always go to stderr, so they never mix with output
+	verbosity = levelQuiet
+	logger    = log.New(os.Stderr, "", 0)
+)
+
+func verbosef(format string, args ...interface{}) {
+	if verbosity >= levelVerbose {
+		logger.Printf(format, args...)
+	}
+}
+
+func debugf(format string, args ...interface{}) {
+	if verbosity >= levelDebug {
+		logger.Printf("debug: "+format, args...)
+	}
+}
+
+// fileError locates a problem in one of the markdown files, e.g. "today.md:1: heading '2026-13-01' is not a valid date"
+type fileError struct {
+	file   string
+	line   int
+	reason error
+}
+
+func (e *fileError) Error() string {
+	if e.line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.file, e.line, e.reason)
+	}
+	return fmt.Sprintf("%s: %v", e.file, e.reason)
+}
+
+func (e *fileError) Unwrap() error {
+	return e.reason
+}
+
+// errorAt makes a fileError, naming the file relative to the base directory where possible
+func (t tasks) errorAt(line int, reason error) error {
+	name := t.file
+	if name == "" {
+		name = "<input>"
+	} else if base, err := getBaseDir(); err == nil {
+		if rel, err := filepath.Rel(base, name); err == nil && !filepath.IsAbs(rel) && rel[0] != '.' {
+			name = rel
+		}
+	}
+	return &fileError{file: name, line: line, reason: reason}
+}
+
+// lineOf is the (1-based) number of the first source line which matches, or 0
+func (t tasks) lineOf(match func(line string) bool) int {
+	sc := bufio.NewScanner(bytes.NewReader(t.src))
+	for n := 1; sc.Scan(); n++ {
+		if match(sc.Text()) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// dueNow lists open tasks in today.md with a time of day which has passed (or is within the given window).
+// It's intended for shell prompts and notifications
+func dueNow(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	within := fs.Duration("within", 0, "include tasks due within this long from now")
+	count := fs.Bool("count", false, "just print the number of tasks")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	today, err := loadToday()
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(*within)
+	due := []task{}
+	for _, t := range flatten(today.Tasks()) {
+		if t.At.IsZero() || t.IsClosed() || t.At.After(cutoff) {
+			continue
+		}
+		due = append(due, t)
+	}
+	if *count {
+		fmt.Println(len(due))
+		return nil
+	}
+	for _, t := range due {
+		fmt.Printf("%s [%s] %s\n", t.At.Format("15:04"), t.Status, t.Description)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// statusCycle is the order statuses are toggled through. Any other statuses follow
+var statusCycle = []string{" ", "i", "x", "p", "c"}
+
+// nextStatus is the status after s, in statusCycle
+func nextStatus(s string) string {
+	order := append([]string{}, statusCycle...)
+	others := []string{}
+	for k := range statuses {
+		if !containsFold(order, k) {
+			others = append(others, k)
+		}
+	}
+	sort.Strings(others)
+	order = append(order, others...)
+	for i, k := range order {
+		if k == s {
+			return order[(i+1)%len(order)]
+		}
+	}
+	return order[0]
+}
+
+// setStatus replaces the item's status marker, or adds one
+func setStatus(item *blackfriday.Node, status string) {
+	p := item.FirstChild
+	if p == nil || p.Type != blackfriday.Paragraph {
+		return
+	}
+	t := p.FirstChild
+	if t == nil || t.Type != blackfriday.Text {
+		t = blackfriday.NewNode(blackfriday.Text)
+		if p.FirstChild != nil {
+			p.FirstChild.InsertBefore(t)
+		} else {
+			p.AppendChild(t)
+		}
+	}
+	if _, ok := parseStatus(string(t.Literal)); ok {
+		t.Literal = append([]byte("["+status+"]"), t.Literal[3:]...)
+		return
+	}
+	t.Literal = append([]byte("["+status+"] "), t.Literal...)
+}
+
+// shiftItem moves an item up or down within its list, returning false at either end
+func shiftItem(item *blackfriday.Node, up bool) bool {
+	if up {
+		prev := item.Prev
+		if prev == nil {
+			return false
+		}
+		item.Unlink()
+		prev.InsertBefore(item)
+		return true
+	}
+	next := item.Next
+	if next == nil {
+		return false
+	}
+	item.Unlink()
+	if next.Next != nil {
+		next.Next.InsertBefore(item)
+	} else {
+		next.Parent.AppendChild(item)
+	}
+	return true
+}
+
+// removeItem unlinks an item (and so its subtasks), along with its list if that is left empty
+func removeItem(item *blackfriday.Node) {
+	list := item.Parent
+	item.Unlink()
+	if list != nil && list.Type == blackfriday.List && list.FirstChild == nil {
+		list.Unlink()
+	}
+}
+
+// moveToSection moves an item (with its subtasks) to the end of a section, adding the section if need be
+func (t tasks) moveToSection(item *blackfriday.Node, section string) {
+	removeItem(item)
+	t.SectionList(section).AppendChild(item)
+}
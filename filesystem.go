@@ -1,23 +1,74 @@
 package main
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 const (
 	todayDir      = "today"
+	listsDir      = "lists"
+	defaultList   = "default"
 	todayBase     = "today.md"
 	recurringBase = "recurring.md"
+	laterBase     = "later.md"
+	configBase    = "config.json"
+	templateBase  = "template.md"
+
+	laterScheduled = "Scheduled"
+	laterSomeday   = "Someday"
 )
 
+// currentList is the named list (or workspace) chosen with --list. The default list lives in the top directory itself
+var currentList = ""
+
 func getBaseDir() (string, error) {
+	return getListDir(currentList)
+}
+
+// getListDir is the directory of a named list, holding its own today.md, recurring.md, archives etc
+func getListDir(name string) (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(homeDir, todayDir), nil
+	if name == "" || name == defaultList {
+		return filepath.Join(homeDir, todayDir), nil
+	}
+	return filepath.Join(homeDir, todayDir, listsDir, name), nil
+}
+
+// getListNames lists the default list and then the named lists which exist
+func getListNames() ([]string, error) {
+	top, err := getListDir(defaultList)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{defaultList}
+	entries, err := ioutil.ReadDir(filepath.Join(top, listsDir))
+	if os.IsNotExist(err) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func validListName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid list name '%s'", name)
+	}
+	return nil
 }
 
 func getTodayFilename() (string, error) {
@@ -36,10 +87,109 @@ func getRecurringFilename() (string, error) {
 	return filepath.Join(base, recurringBase), nil
 }
 
+func getLaterFilename() (string, error) {
+	base, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, laterBase), nil
+}
+
+func getConfigFilename() (string, error) {
+	base, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, configBase), nil
+}
+
+func getTemplateFilename() (string, error) {
+	base, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, templateBase), nil
+}
+
 func getArchiveFilename(forTime time.Time) (string, error) {
 	base, err := getBaseDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(base, forTime.Format(filepath.Join("2006", "01", "02"))+".today.md"), nil
+	return archiveFilename(base, forTime), nil
+}
+
+func archiveFilename(base string, forTime time.Time) string {
+	return filepath.Join(base, forTime.Format(filepath.Join("2006", "01", "02"))+".today.md")
+}
+
+// fileDay is the day a file is for, going by its path for an archive (e.g. 2026/10/19.today.md),
+// and otherwise by when it was last changed
+func fileDay(file string) (time.Time, bool) {
+	if abs, err := filepath.Abs(file); err == nil {
+		dir := filepath.Dir(abs)
+		rel := filepath.Join(filepath.Base(filepath.Dir(dir)), filepath.Base(dir), filepath.Base(abs))
+		if d, err := time.ParseInLocation(filepath.Join("2006", "01", "02")+".today.md", rel, time.Local); err == nil {
+			return d, true
+		}
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day(fi.ModTime()), true
+}
+
+func getRecurringArchiveFilename(forTime time.Time) (string, error) {
+	base, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, forTime.Format(filepath.Join("2006", "01", "02"))+".recurring.md"), nil
+}
+
+func getWeekReviewFilename(forTime time.Time) (string, error) {
+	base, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	year, week := forTime.ISOWeek()
+	return filepath.Join(base, fmt.Sprintf("%d", year), fmt.Sprintf("W%02d.review.md", week)), nil
+}
+
+func getMonthReviewFilename(forTime time.Time) (string, error) {
+	base, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, forTime.Format(filepath.Join("2006", "01"))+".review.md"), nil
+}
+
+// getArchiveDates lists the days which have an archive, in order
+func getArchiveDates() ([]time.Time, error) {
+	base, err := getBaseDir()
+	if err != nil {
+		return nil, err
+	}
+	return archiveDates(base)
+}
+
+func archiveDates(base string) ([]time.Time, error) {
+	matches, err := filepath.Glob(filepath.Join(base, "[0-9]*", "[0-9]*", "[0-9]*.today.md"))
+	if err != nil {
+		return nil, err
+	}
+	dates := []time.Time{}
+	for _, m := range matches {
+		rel, err := filepath.Rel(base, m)
+		if err != nil {
+			return nil, err
+		}
+		d, err := time.ParseInLocation(filepath.Join("2006", "01", "02")+".today.md", rel, time.Local)
+		if err != nil {
+			continue
+		}
+		dates = append(dates, d)
+	}
+	return dates, nil
 }
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// stringsFlag collects a flag which may be given more than once
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// taskFilter matches tasks having all of the given tags, contexts and projects, and any of the given statuses
+type taskFilter struct {
+	tags     stringsFlag
+	contexts stringsFlag
+	projects stringsFlag
+	statuses stringsFlag
+	group    string
+}
+
+func addFilterFlags(fs *flag.FlagSet) *taskFilter {
+	f := &taskFilter{}
+	fs.Var(&f.tags, "tag", "only tasks with this #tag (repeatable)")
+	fs.Var(&f.contexts, "context", "only tasks with this @context (repeatable)")
+	fs.Var(&f.projects, "project", "only tasks with this +project (repeatable)")
+	fs.Var(&f.statuses, "status", "only tasks with this status, e.g. 'x' or 'Done' (repeatable)")
+	fs.StringVar(&f.group, "group", "section", "group by one of section, tag, context, project, status or list")
+	return f
+}
+
+func (f *taskFilter) validate() error {
+	switch f.group {
+	case "section", "tag", "context", "project", "status", "list":
+	default:
+		return fmt.Errorf("cannot group by '%s'", f.group)
+	}
+	for _, s := range f.statuses {
+		if _, ok := statusKey(s); !ok {
+			return fmt.Errorf("unknown status '%s'", s)
+		}
+	}
+	return nil
+}
+
+func (f *taskFilter) match(t task) bool {
+	for _, tag := range f.tags {
+		if !containsFold(t.Tags, strings.TrimPrefix(tag, "#")) {
+			return false
+		}
+	}
+	for _, c := range f.contexts {
+		if !containsFold(t.Contexts, strings.TrimPrefix(c, "@")) {
+			return false
+		}
+	}
+	for _, p := range f.projects {
+		if !containsFold(t.Projects, strings.TrimPrefix(p, "+")) {
+			return false
+		}
+	}
+	if len(f.statuses) == 0 {
+		return true
+	}
+	for _, s := range f.statuses {
+		if k, _ := statusKey(s); k == t.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// groups returns the group names for a task. A task may belong to several groups (e.g. when it has 2 tags)
+func (f *taskFilter) groups(t task) []string {
+	var (
+		g      []string
+		prefix string
+	)
+	switch f.group {
+	case "tag":
+		g, prefix = t.Tags, "#"
+	case "context":
+		g, prefix = t.Contexts, "@"
+	case "project":
+		g, prefix = t.Projects, "+"
+	case "status":
+		return []string{statusName(t.Status)}
+	case "list":
+		return []string{t.List}
+	default:
+		return []string{t.Section}
+	}
+	if len(g) == 0 {
+		return []string{"(none)"}
+	}
+	ret := []string{}
+	for _, v := range g {
+		ret = append(ret, prefix+v)
+	}
+	return ret
+}
+
+// statusKey accepts either a key from `statuses` (e.g. "x") or its name (e.g. "Done")
+func statusKey(s string) (string, bool) {
+	if s == "" {
+		s = " "
+	}
+	for k, name := range statuses {
+		if strings.EqualFold(s, k) || strings.EqualFold(s, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func statusName(k string) string {
+	if name, ok := statuses[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown [%s]", k)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTask finds the one task whose description contains sel (case insensitive). An exact match wins
+func selectTask(ts []task, sel string) (task, error) {
+	matches := []task{}
+	for _, t := range ts {
+		if strings.EqualFold(t.Description, sel) {
+			return t, nil
+		}
+		if strings.Contains(strings.ToLower(t.Description), strings.ToLower(sel)) {
+			matches = append(matches, t)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return task{}, fmt.Errorf("no task matching '%s'", sel)
+	case 1:
+		return matches[0], nil
+	}
+	descs := []string{}
+	for _, t := range matches {
+		descs = append(descs, fmt.Sprintf("'%s'", t.Description))
+	}
+	return task{}, fmt.Errorf("'%s' matches %d tasks: %s", sel, len(matches), strings.Join(descs, ", "))
+}
@@ -3,7 +3,9 @@ module github.com/laher/today
 go 1.14
 
 require (
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/gomarkdown/markdown v0.0.0-20200316172748-fd1f3374857d
 	github.com/laher/markdownfmt v0.0.0-20200418103851-59147d230740
 	github.com/russross/blackfriday/v2 v2.0.1
+	golang.org/x/term v0.0.0-20210503060354-a79de5458b56
 )
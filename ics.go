@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsStatuses maps statuses to iCalendar VTODO STATUS values (RFC 5545 3.8.1.11).
+// Others are exported as NEEDS-ACTION, with X-TODAY-STATUS so that they survive a round trip
+var icsStatuses = map[string]string{
+	" ": "NEEDS-ACTION",
+	"i": "IN-PROCESS",
+	"x": "COMPLETED",
+	"c": "CANCELLED",
+}
+
+func writeICS(w io.Writer, ts []exportTask) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		// lines are folded at 75 octets, including the leading space of continuations, without splitting characters
+		for limit := 75; len(s) > limit; limit = 74 {
+			i := limit
+			for !utf8.RuneStart(s[i]) {
+				i--
+			}
+			bw.WriteString(s[:i] + "\r\n ")
+			s = s[i:]
+		}
+		bw.WriteString(s + "\r\n")
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//laher//today//EN")
+	for _, t := range ts {
+		line("BEGIN:VTODO")
+		line("UID:" + t.uid)
+		line("DTSTAMP:" + stamp)
+		line("SUMMARY:" + icsEscape(plainDescription(t.Description)))
+		status, ok := icsStatuses[t.Status]
+		if !ok {
+			status = "NEEDS-ACTION"
+			line("X-TODAY-STATUS:" + icsEscape(t.Status))
+		}
+		line("STATUS:" + status)
+		if t.Priority > 0 {
+			line("PRIORITY:" + strconv.Itoa(icsPriority(t.Priority)))
+		}
+		if len(t.Tags) > 0 {
+			cats := []string{}
+			for _, tag := range t.Tags {
+				cats = append(cats, icsEscape(tag))
+			}
+			line("CATEGORIES:" + strings.Join(cats, ","))
+		}
+		var (
+			due, start           time.Time
+			dueTimed, startTimed bool
+		)
+		switch {
+		case !t.Due.IsZero() && !t.At.IsZero():
+			due, dueTimed = time.Date(t.Due.Year(), t.Due.Month(), t.Due.Day(), t.At.Hour(), t.At.Minute(), 0, 0, time.Local), true
+		case !t.Due.IsZero():
+			due = t.Due
+		case !t.At.IsZero() && t.Recurrence == nil:
+			due, dueTimed = t.At, true
+		}
+		start = t.Scheduled
+		if r := t.Recurrence; r != nil && !r.AfterDone && (len(r.Times) > 0 || start.IsZero()) {
+			// a recurrence needs a start
+			start = t.from
+			if start.IsZero() {
+				start = day(time.Now())
+			}
+			if len(r.Times) > 0 {
+				start, startTimed = start.Add(time.Duration(r.Times[0])*time.Minute), true
+			}
+		}
+		// DUE and DTSTART must have the same value type, so a date goes with a date-time as midnight
+		timed := dueTimed || startTimed
+		if !due.IsZero() {
+			line("DUE" + icsValue(due, timed))
+		}
+		if !start.IsZero() {
+			line("DTSTART" + icsValue(start, timed))
+		}
+		if r := t.Recurrence; r != nil {
+			if r.AfterDone {
+				// not a calendar rule, so it is only kept for today
+				line("X-TODAY-AFTER-DONE:" + r.afterDoneInterval())
+			} else {
+				rule := r.String()
+				if !t.until.IsZero() {
+					// UNTIL has the value type of DTSTART, and includes the whole day
+					rule += ";UNTIL=" + icsFormat(day(t.until).Add(24*time.Hour-time.Second), timed)
+				}
+				line("RRULE:" + rule)
+			}
+			if len(r.Times) > 0 && !r.timesInRule() {
+				// times which BYHOUR and BYMINUTE can't hold, e.g. 09:30 and 17:00
+				clocks := []string{}
+				for _, mins := range r.Times {
+					clocks = append(clocks, formatClock(mins))
+				}
+				line("X-TODAY-AT:" + strings.Join(clocks, ","))
+			}
+		}
+		line("END:VTODO")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+func readICS(r io.Reader) ([]task, error) {
+	var (
+		sc    = bufio.NewScanner(r)
+		lines = []string{}
+	)
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			// unfold
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	var (
+		ret      = []task{}
+		t        *task
+		icsState string
+		todayKey string
+		todayAt  []int
+	)
+	for n, l := range lines {
+		name, params, value := icsProperty(l)
+		switch {
+		case name == "BEGIN" && value == "VTODO":
+			t = &task{Status: " "}
+			icsState, todayKey, todayAt = "", "", nil
+			continue
+		case t == nil:
+			continue
+		case name == "END" && value == "VTODO":
+			if k, ok := statusKey(todayKey); todayKey != "" && ok {
+				t.Status = k
+			} else {
+				for k, s := range icsStatuses {
+					if s == icsState {
+						t.Status = k
+					}
+				}
+			}
+			if t.Recurrence != nil && len(todayAt) > 0 {
+				t.Recurrence.Times = todayAt
+			}
+			if t.Description != "" {
+				ret = append(ret, *t)
+			}
+			t = nil
+			continue
+		}
+		switch name {
+		case "SUMMARY":
+			t.Description = strings.Join(strings.Fields(icsUnescape(value)), " ")
+		case "STATUS":
+			icsState = strings.ToUpper(value)
+		case "PRIORITY":
+			// as icsPriority writes them, with the values in between going to the higher priority. 0 is undefined
+			switch n, _ := strconv.Atoi(value); {
+			case n >= 1 && n <= 2:
+				t.Priority = 1
+			case n >= 3 && n <= 4:
+				t.Priority = 2
+			case n >= 5 && n <= 6:
+				t.Priority = 3
+			case n >= 7 && n <= 9:
+				t.Priority = 4
+			}
+		case "X-TODAY-STATUS":
+			todayKey = icsUnescape(value)
+		case "X-TODAY-AFTER-DONE":
+			if r, _, err := parseRecurrence("after-done:" + value); err == nil && r != nil {
+				t.Recurrence = r
+			} else {
+				verbosef("line %d: after-done '%s' is not valid", n+1, value)
+			}
+		case "X-TODAY-AT":
+			for _, c := range icsSplit(value) {
+				if mins, ok := parseClock(c); ok {
+					todayAt = append(todayAt, mins)
+				}
+			}
+		case "CATEGORIES":
+			for _, c := range icsSplit(value) {
+				if c = strings.Join(strings.Fields(icsUnescape(c)), "-"); c != "" {
+					t.Tags = append(t.Tags, c)
+				}
+			}
+		case "DUE", "DTSTART":
+			d, timed, err := icsTime(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n+1, err)
+			}
+			if name == "DUE" {
+				t.Due = day(d)
+			} else {
+				t.Scheduled = day(d)
+			}
+			// dates are written as midnight alongside a date-time
+			if timed && (d.Hour() != 0 || d.Minute() != 0) {
+				t.At = d
+			}
+		case "RRULE":
+			rule, until, err := icsRRule(value)
+			if err != nil {
+				verbosef("line %d: %v, so it is imported as a one off", n+1, err)
+				continue
+			}
+			t.Recurrence, t.Until = rule, until
+		}
+	}
+	for i := range ret {
+		if ret[i].Recurrence != nil {
+			// the start of a recurring task is where its rule begins, rather than a scheduled date
+			ret[i].From, ret[i].Scheduled = ret[i].Scheduled, time.Time{}
+		}
+	}
+	return ret, nil
+}
+
+// icsPriority is a priority as a PRIORITY value: p1 to p4 are 1, 3, 5 and 9. Lower priorities are 9
+func icsPriority(p int) int {
+	switch p {
+	case 1:
+		return 1
+	case 2:
+		return 3
+	case 3:
+		return 5
+	}
+	return 9
+}
+
+// icsProperty splits a content line such as `DUE;VALUE=DATE:20261020`
+func icsProperty(l string) (string, map[string]string, string) {
+	i := strings.IndexByte(l, ':')
+	if i < 0 {
+		return "", nil, ""
+	}
+	parts := strings.Split(l[:i], ";")
+	params := map[string]string{}
+	for _, p := range parts[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, l[i+1:]
+}
+
+// icsTime reads a DATE or DATE-TIME value, in local time
+func icsTime(value string, params map[string]string) (time.Time, bool, error) {
+	loc := time.Local
+	if tz, ok := params["TZID"]; ok {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	if len(value) == 8 {
+		d, err := time.ParseInLocation("20060102", value, time.Local)
+		return d, false, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		d, err := time.Parse("20060102T150405Z", value)
+		return d.In(time.Local), true, err
+	}
+	d, err := time.ParseInLocation("20060102T150405", value, loc)
+	return d.In(time.Local), true, err
+}
+
+// icsRRule reads the supported subset of an RRULE, with its UNTIL date
+func icsRRule(value string) (*recurrence, time.Time, error) {
+	var (
+		parts = []string{}
+		until time.Time
+	)
+	for _, p := range strings.Split(value, ";") {
+		kv := strings.SplitN(strings.ToUpper(p), "=", 2)
+		switch kv[0] {
+		case "UNTIL":
+			if len(kv) == 2 && len(kv[1]) >= 8 {
+				d, err := time.ParseInLocation("20060102", kv[1][:8], time.Local)
+				if err != nil {
+					return nil, until, err
+				}
+				until = d
+			}
+		case "WKST":
+		default:
+			parts = append(parts, p)
+		}
+	}
+	r, err := parseRRule(strings.Join(parts, ";"))
+	return r, until, err
+}
+
+var (
+	icsEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	icsUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+// icsValue is the parameters and value of a DATE or DATE-TIME property, e.g. `;VALUE=DATE:20261020`
+func icsValue(d time.Time, timed bool) string {
+	if timed {
+		return ":" + icsFormat(d, true)
+	}
+	return ";VALUE=DATE:" + icsFormat(d, false)
+}
+
+// icsFormat is a DATE or (local) DATE-TIME value
+func icsFormat(d time.Time, timed bool) string {
+	if timed {
+		return d.Format("20060102T150405")
+	}
+	return d.Format("20060102")
+}
+
+// icsSplit splits a list value, such as CATEGORIES, on the commas which aren't escaped
+func icsSplit(s string) []string {
+	var (
+		ret     = []string{}
+		start   = 0
+		escaped = false
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == ',':
+			ret = append(ret, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(ret, s[start:])
+}
+
+func icsEscape(s string) string {
+	return icsEscaper.Replace(s)
+}
+
+func icsUnescape(s string) string {
+	return icsUnescaper.Replace(s)
+}
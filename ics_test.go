@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICSRecurrence(t *testing.T) {
+	from := time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)
+	until := time.Date(2026, 12, 31, 0, 0, 0, 0, time.Local)
+	for _, tc := range []struct {
+		name     string
+		rule     *recurrence
+		contains []string
+		excludes []string
+	}{
+		{"after-done", &recurrence{Freq: daily, Interval: 3, AfterDone: true}, []string{"X-TODAY-AFTER-DONE:3d"}, []string{"RRULE", "DTSTART"}},
+		{"dates", &recurrence{Freq: weekly, Interval: 1}, []string{"DTSTART;VALUE=DATE:20261019", "RRULE:FREQ=WEEKLY;UNTIL=20261231\r"}, nil},
+		{"times", &recurrence{Freq: daily, Interval: 1, Times: []int{570, 1020}},
+			[]string{"DTSTART:20261019T093000", "RRULE:FREQ=DAILY;UNTIL=20261231T235959", "X-TODAY-AT:09:30,17:00"}, []string{"BYHOUR"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := exportTask{task: task{Status: " ", Description: "Water the plants", Recurrence: tc.rule}, uid: "1@today"}
+			if !tc.rule.AfterDone {
+				e.from, e.until = from, until
+			}
+			var b strings.Builder
+			if err := writeICS(&b, []exportTask{e}); err != nil {
+				t.Fatal(err)
+			}
+			out := b.String()
+			for _, s := range tc.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("expected %q in:\n%s", s, out)
+				}
+			}
+			for _, s := range tc.excludes {
+				if strings.Contains(out, s) {
+					t.Errorf("unexpected %q in:\n%s", s, out)
+				}
+			}
+			ts, err := readICS(strings.NewReader(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ts) != 1 || ts[0].Recurrence == nil {
+				t.Fatalf("expected a recurring task, got %+v", ts)
+			}
+			r := ts[0].Recurrence
+			if r.String() != tc.rule.String() || r.AfterDone != tc.rule.AfterDone || !equalInts(r.Times, tc.rule.Times) {
+				t.Errorf("read %s (times %v), expected %s (times %v)", r, r.Times, tc.rule, tc.rule.Times)
+			}
+			if !tc.rule.AfterDone && !ts[0].Until.Equal(until) {
+				t.Errorf("until is %s, expected %s", ts[0].Until, until)
+			}
+		})
+	}
+}
+
+func TestICSPriority(t *testing.T) {
+	for p := 1; p <= 4; p++ {
+		var b strings.Builder
+		e := exportTask{task: task{Status: " ", Description: "Call mum", Priority: p}, uid: "1@today"}
+		if err := writeICS(&b, []exportTask{e}); err != nil {
+			t.Fatal(err)
+		}
+		ts, err := readICS(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ts) != 1 || ts[0].Priority != p {
+			t.Errorf("p%d is read back as %+v", p, ts)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"crypto/sha1"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// taskFormat converts tasks to and from another tool's format
+type taskFormat struct {
+	write func(w io.Writer, ts []exportTask) error
+	read  func(r io.Reader) ([]task, error)
+}
+
+var taskFormats = map[string]taskFormat{
+	"ics":         {write: writeICS, read: readICS},
+	"org":         {write: writeOrg, read: readOrg},
+	"taskwarrior": {write: writeTaskwarrior, read: readTaskwarrior},
+	"todotxt":     {write: writeTodoTxt, read: readTodoTxt},
+}
+
+// exportTask is a task from today.md, or a recurring one from recurring.md
+type exportTask struct {
+	task
+	uid         string
+	from, until time.Time // for recurring tasks
+}
+
+func formatNames() []string {
+	names := []string{}
+	for name := range taskFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getFormat(name string) (taskFormat, error) {
+	if name == "" {
+		return taskFormat{}, fmt.Errorf("specify a --format: %s", strings.Join(formatNames(), ", "))
+	}
+	f, ok := taskFormats[name]
+	if !ok {
+		return taskFormat{}, fmt.Errorf("unknown format '%s' (expected one of %s)", name, strings.Join(formatNames(), ", "))
+	}
+	return f, nil
+}
+
+// plainDescription is a description without its priority, dates and recurrence, which formats have their own fields for
+func plainDescription(desc string) string {
+	desc = stripRecurrenceText(desc)
+	desc = scheduledRegexp.ReplaceAllString(desc, "")
+	desc = dueRegexp.ReplaceAllString(desc, "")
+	desc = historyRegexp.ReplaceAllString(desc, "")
+	_, desc = splitPriority(desc)
+	return desc
+}
+
+// historyRegexp matches the dates a task was created and completed
+var historyRegexp = regexp.MustCompile(`\s*\b(created|completed):\S+`)
+
+// stableUID identifies a task across days (as its section changes), and across exports.
+// n numbers repeated tasks
+func stableUID(kind, desc string, n int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d", listName(), kind, recurringKey(desc), n)))
+	return fmt.Sprintf("%x@today", sum[:8])
+}
+
+// loadExportTasks loads today's tasks and, optionally, the recurring ones
+func loadExportTasks(withRecurring bool) ([]exportTask, error) {
+	ret := []exportTask{}
+	seen := map[string]int{}
+	add := func(kind string, t task) exportTask {
+		seen[kind+recurringKey(t.Description)]++
+		e := exportTask{task: t, uid: stableUID(kind, t.Description, seen[kind+recurringKey(t.Description)])}
+		return e
+	}
+	today, err := loadToday()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range flatten(today.Tasks()) {
+		ret = append(ret, add("today", t))
+	}
+	if !withRecurring {
+		return ret, nil
+	}
+	recurring, err := loadRecurring()
+	if os.IsNotExist(err) {
+		return ret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range recurring.Tasks() {
+		r, from, until := itemRecurrence(t.node, t.Section)
+		if r == nil {
+			continue
+		}
+		// `every` may be written without its colon, which plainDescription wouldn't recognise
+		if _, rest, err := parseRecurringText(t.Description); err == nil {
+			t.Description = strings.Join(strings.Fields(rest), " ")
+		}
+		// including any at:09:30
+		rc := *r
+		rc.Times = itemTimes(r, inlineText(t.node.FirstChild))
+		t.Recurrence = &rc
+		e := add("recurring", t)
+		e.from, e.until = from, until
+		ret = append(ret, e)
+	}
+	return ret, nil
+}
+
+func exportCmd(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	format := fs.String("format", "", "one of "+strings.Join(formatNames(), ", "))
+	out := fs.String("o", "", "write to this file, instead of stdout")
+	withRecurring := fs.Bool("recurring", true, "include the tasks from recurring.md")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	f, err := getFormat(*format)
+	if err != nil {
+		return err
+	}
+	ts, err := loadExportTasks(*withRecurring)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return f.write(os.Stdout, ts)
+	}
+	fh, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := f.write(fh, ts); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// importCmd adds tasks to the Inbox, and recurring ones to recurring.md. Tasks which are already there are skipped
+func importCmd(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	format := fs.String("format", "", "one of "+strings.Join(formatNames(), ", "))
+	dryRun := fs.Bool("dryrun", false, "print what would be imported, without changing anything")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	f, err := getFormat(*format)
+	if err != nil {
+		return err
+	}
+	imported := []task{}
+	if fs.NArg() == 0 {
+		if imported, err = f.read(os.Stdin); err != nil {
+			return err
+		}
+	}
+	for _, name := range fs.Args() {
+		fh, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		ts, err := f.read(fh)
+		fh.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		imported = append(imported, ts...)
+	}
+	today, err := loadToday()
+	if err != nil {
+		return err
+	}
+	recurring, err := loadRecurring()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if os.IsNotExist(err) {
+		recurring, err = parse([]byte("# Recurring tasks\n"))
+		if err != nil {
+			return err
+		}
+	}
+	present := map[string]bool{}
+	for _, t := range append(flatten(today.Tasks()), recurring.Tasks()...) {
+		present[recurringKey(t.Description)] = true
+	}
+	added, addedRecurring := 0, 0
+	for _, t := range imported {
+		key := recurringKey(t.Description)
+		if present[key] {
+			verbosef("skipping '%s', which is already there", t.Description)
+			continue
+		}
+		present[key] = true
+		line := taskLine(t)
+		if t.Recurrence != nil {
+			fmt.Printf("recurring: %s\n", line)
+			itemNode(recurring.SectionList(importedSection), line)
+			addedRecurring++
+			continue
+		}
+		fmt.Printf("Inbox: %s\n", line)
+		itemNode(today.SectionList("Inbox"), line)
+		added++
+	}
+	if *dryRun {
+		return nil
+	}
+	if added > 0 {
+		if err := newFile(today.file, today); err != nil {
+			return err
+		}
+	}
+	if addedRecurring > 0 {
+		if err := newRecurring(recurring); err != nil {
+			return err
+		}
+	}
+	if added+addedRecurring == 0 && len(imported) > 0 {
+		return errors.New("nothing new to import")
+	}
+	return nil
+}
+
+// importedSection is where recurring tasks are imported, in recurring.md
+const importedSection = "Imported"
+
+// taskLine writes an imported task as the text of a list item, with its metadata as tokens
+func taskLine(t task) string {
+	s := "[" + t.Status + "] "
+	if p, _ := splitPriority(t.Description); t.Priority > 0 && p == 0 {
+		s += "(" + priorityLetter(t.Priority) + ") "
+	}
+	s += t.Description
+	words := strings.Fields(strings.ToLower(t.Description))
+	add := func(prefix string, vals []string) {
+		for _, v := range vals {
+			if !containsFold(words, prefix+v) {
+				s += " " + prefix + v
+			}
+		}
+	}
+	add("#", t.Tags)
+	add("@", t.Contexts)
+	add("+", t.Projects)
+	if !t.Due.IsZero() {
+		s += " due:" + t.Due.Format("2006-01-02")
+	}
+	if !t.Scheduled.IsZero() {
+		s += " scheduled:" + t.Scheduled.Format("2006-01-02")
+	}
+	if !t.Created.IsZero() {
+		s += " created:" + t.Created.Format("2006-01-02")
+	}
+	if !t.Completed.IsZero() {
+		s += " completed:" + t.Completed.Format("2006-01-02")
+	}
+	// times which a rule can't hold are written as at:, which recurring.md adds to the rule's
+	ats := []int{}
+	if r := t.Recurrence; r != nil && !r.timesInRule() {
+		ats = append(ats, r.Times...)
+	}
+	if at := t.At.Hour()*60 + t.At.Minute(); !t.At.IsZero() && (t.Recurrence == nil || !containsInt(t.Recurrence.Times, at)) {
+		ats = append(ats, at)
+	}
+	for _, at := range ats {
+		s += " at:" + formatClock(at)
+	}
+	if r := t.Recurrence; r != nil && r.AfterDone {
+		s += " after-done:" + r.afterDoneInterval()
+	} else if r != nil {
+		s += " rrule:" + r.String()
+		if !t.From.IsZero() {
+			s += " from:" + t.From.Format("2006-01-02")
+		}
+		if !t.Until.IsZero() {
+			s += " until:" + t.Until.Format("2006-01-02")
+		}
+	}
+	return s
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+var (
+	recurringSections = []string{"Daily", "Weekly", "Weekdays"}
+
+	headingLine = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	itemLine    = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.*)$`)
+	markerLike  = regexp.MustCompile(`^\[[^\]]{0,3}\]`)
+)
+
+// lintProblem is a structural problem in a file, at a line
+type lintProblem struct {
+	file    string
+	line    int
+	msg     string
+	fixable bool
+}
+
+func (p lintProblem) String() string {
+	s := fmt.Sprintf("%s:%d: %s", p.file, p.line, p.msg)
+	if p.fixable {
+		s += " (fixable)"
+	}
+	return s
+}
+
+func lint(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fix := fs.Bool("fix", false, "repair the problems which can be repaired")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		for _, get := range []func() (string, error){getTodayFilename, getRecurringFilename, getLaterFilename} {
+			f, err := get()
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(f); err == nil {
+				files = append(files, f)
+			}
+		}
+	}
+	remaining := 0
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		problems := lintFile(filepath.Base(f), b, cfg)
+		fixed := false
+		if *fix {
+			for _, p := range problems {
+				fixed = fixed || p.fixable
+			}
+		}
+		for _, p := range problems {
+			if fixed && p.fixable {
+				fmt.Printf("%s:%d: fixed: %s\n", p.file, p.line, p.msg)
+				continue
+			}
+			fmt.Println(p)
+			remaining++
+		}
+		if fixed {
+			t, err := parse(separateHeadings(b))
+			if err != nil {
+				return err
+			}
+			if err := newFile(f, lintFix(f, t, cfg)); err != nil {
+				return err
+			}
+		}
+	}
+	if remaining > 0 {
+		return fmt.Errorf("%d problem(s)", remaining)
+	}
+	return nil
+}
+
+// lintFile checks a today.md, recurring.md, later.md, or an archive, line by line
+func lintFile(name string, b []byte, cfg config) []lintProblem {
+	var (
+		problems  = []lintProblem{}
+		sc        = bufio.NewScanner(bytes.NewReader(b))
+		lineNo    = 0
+		fence     = ""
+		firstHead = true
+		section   = ""
+		sections  = map[string]int{}
+		prevItem  = false
+		isToday   = name != recurringBase && name != laterBase
+		known     = cfg.sectionNames()
+	)
+	if name == recurringBase {
+		known = recurringSections
+	}
+	add := func(msg string, fixable bool) {
+		problems = append(problems, lintProblem{file: name, line: lineNo, msg: msg, fixable: fixable})
+	}
+	for sc.Scan() {
+		lineNo++
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if fence == "" {
+				fence = trimmed[:3]
+			} else if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		wasItem := prevItem
+		prevItem = itemLine.MatchString(line) || (prevItem && trimmed != "")
+		if m := headingLine.FindStringSubmatch(line); m != nil {
+			level, text := len(m[1]), m[2]
+			if wasItem {
+				add("heading follows a list item without a blank line, so it is read as part of the item", true)
+			}
+			if firstHead && isToday {
+				if level != 1 {
+					add("missing date heading (e.g. '# "+time.Now().Format("2006-01-02, Monday")+"')", true)
+				} else if err := checkDateHeading(text); err != nil {
+					add(err.Error(), false)
+				}
+			}
+			firstHead = false
+			if level == 1 {
+				// archives may have several days appended
+				sections = map[string]int{}
+				section = ""
+			}
+			if level != 2 {
+				continue
+			}
+			section = text
+			if first, ok := sections[text]; ok {
+				add(fmt.Sprintf("duplicate section '%s' (first at line %d)", text, first), true)
+				continue
+			}
+			sections[text] = lineNo
+			if name == laterBase || knownSection(known, text) != "" {
+				continue
+			}
+			if s := closestSection(known, text); s != "" {
+				add(fmt.Sprintf("unknown section '%s' (did you mean '%s'?)", text, s), true)
+			} else if isToday {
+				add(fmt.Sprintf("unknown section '%s' is dropped at rollover", text), false)
+			}
+			continue
+		}
+		m := itemLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if firstHead && isToday {
+			add("missing date heading (e.g. '# "+time.Now().Format("2006-01-02, Monday")+"')", true)
+			firstHead = false
+		}
+		text := m[1]
+		indented := line[0] == ' ' || line[0] == '\t'
+		if section == "" && !indented {
+			add("item outside any section", isToday)
+		}
+		if status, ok := parseStatus(text); ok {
+			if _, known := statuses[status]; !known {
+				add(fmt.Sprintf("unknown status '%s'", text[:3]), false)
+			}
+		} else if markerLike.MatchString(text) {
+			add(fmt.Sprintf("malformed status '%s'", markerLike.FindString(text)), false)
+		}
+		for _, msg := range lintTokens(name, section, text) {
+			add(msg, false)
+		}
+	}
+	if firstHead && isToday {
+		lineNo = 1
+		add("missing date heading (e.g. '# "+time.Now().Format("2006-01-02, Monday")+"')", true)
+	}
+	return problems
+}
+
+// separateHeadings adds a blank line before any heading which directly follows other text
+func separateHeadings(b []byte) []byte {
+	var (
+		out   bytes.Buffer
+		sc    = bufio.NewScanner(bytes.NewReader(b))
+		prev  = ""
+		fence = ""
+	)
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if fence == "" {
+				fence = trimmed[:3]
+			} else if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		} else if fence == "" && headingLine.MatchString(line) && strings.TrimSpace(prev) != "" {
+			out.WriteString("\n")
+		}
+		out.WriteString(line + "\n")
+		prev = line
+	}
+	return out.Bytes()
+}
+
+// checkDateHeading is the validation behind getDateHeader, with a reason
+func checkDateHeading(text string) error {
+	if len(text) < 10 {
+		return fmt.Errorf("heading '%s' does not start with a yyyy-mm-dd date", text)
+	}
+	if _, err := time.Parse("2006-01-02", text[:10]); err != nil {
+		return fmt.Errorf("heading '%s' is not a valid date", text[:10])
+	}
+	return nil
+}
+
+// lintTokens checks inline metadata: dates, times and recurrence rules
+func lintTokens(name, section, text string) []string {
+	msgs := []string{}
+	for _, m := range dateTokenRegexp.FindAllStringSubmatch(text, -1) {
+		if _, err := parseDate(m[2], time.Now()); err != nil {
+			msgs = append(msgs, fmt.Sprintf("invalid %s date: %v", m[1], err))
+		}
+	}
+	for _, m := range atRegexp.FindAllStringSubmatch(text, -1) {
+		if _, ok := parseClock(m[1]); !ok {
+			msgs = append(msgs, fmt.Sprintf("invalid time 'at:%s' (expected hh:mm)", m[1]))
+		}
+	}
+	if name != recurringBase {
+		return msgs
+	}
+	for _, m := range boundsRegexp.FindAllStringSubmatch(text, -1) {
+		if _, err := parseDate(m[2], time.Now()); err != nil {
+			msgs = append(msgs, fmt.Sprintf("invalid %s date: %v", m[1], err))
+		}
+	}
+	if m := ifMissed.FindStringSubmatch(text); m != nil {
+		switch m[1] {
+		case missedSkip, missedCarry, missedOverdue:
+		default:
+			msgs = append(msgs, fmt.Sprintf("invalid if-missed '%s' (expected %s, %s or %s)", m[1], missedSkip, missedCarry, missedOverdue))
+		}
+	}
+	r, _, err := parseRecurringText(text)
+	switch {
+	case err != nil:
+		msgs = append(msgs, fmt.Sprintf("invalid recurrence: %v", err))
+	case r == nil && sectionRecurrence(section) == nil && everyWord.MatchString(text):
+		msgs = append(msgs, "'every' is not followed by a rule (e.g. 'every:2nd tuesday' or 'every 2h from 09:00 to 17:00')")
+	case r == nil && sectionRecurrence(section) == nil:
+		msgs = append(msgs, fmt.Sprintf("no recurrence rule, and section '%s' has no default, so this never recurs", section))
+	}
+	return msgs
+}
+
+func knownSection(known []string, text string) string {
+	for _, k := range known {
+		// as per ByHeader
+		if strings.Contains(text, k) {
+			return k
+		}
+	}
+	return ""
+}
+
+// closestSection suggests a known section for a misspelled one
+func closestSection(known []string, text string) string {
+	for _, k := range known {
+		if strings.EqualFold(k, text) || levenshtein(strings.ToLower(k), strings.ToLower(text)) <= 2 {
+			return k
+		}
+	}
+	return ""
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// lintFix repairs a document: adding a missing date heading, renaming misspelled sections,
+// merging duplicate sections, and moving stray items into the Inbox.
+// Archives may have several days appended, so each day (from its level 1 heading) is repaired on its own, as per lintFile
+func lintFix(file string, t tasks, cfg config) tasks {
+	var (
+		name    = filepath.Base(file)
+		isToday = name != recurringBase && name != laterBase
+		known   = cfg.sectionNames()
+		days    = [][]*blackfriday.Node{{}}
+	)
+	if name == recurringBase {
+		known = recurringSections
+	}
+	for n := t.node.FirstChild; n != nil; n = n.Next {
+		if n.Type == blackfriday.Heading && n.Level == 1 && len(days[len(days)-1]) > 0 {
+			days = append(days, []*blackfriday.Node{})
+		}
+		days[len(days)-1] = append(days[len(days)-1], n)
+	}
+	// a missing date heading is added for the day the file is for, rather than today, as rollover goes by it
+	var date time.Time
+	if isToday {
+		var ok bool
+		if date, ok = fileDay(file); !ok {
+			verbosef("%s: not adding a date heading, as its day is not known", file)
+		}
+	}
+	fixed := blackfriday.NewNode(blackfriday.Document)
+	for i, nodes := range days {
+		if i > 0 {
+			date = time.Time{}
+		}
+		lintFixDay(fixed, nodes, name, known, isToday, date)
+	}
+	return tasks{node: fixed}
+}
+
+// lintFixDay repairs the nodes of one day, appending them to doc
+func lintFixDay(doc *blackfriday.Node, nodes []*blackfriday.Node, name string, known []string, isToday bool, date time.Time) {
+	var (
+		preface  = []*blackfriday.Node{}
+		strays   = []*blackfriday.Node{}
+		order    = []string{}
+		headings = map[string]*blackfriday.Node{}
+		content  = map[string][]*blackfriday.Node{}
+		current  = ""
+	)
+	for _, n := range nodes {
+		if n.Type == blackfriday.Heading && n.Level == 2 {
+			current = inlineText(n)
+			if knownSection(known, current) == "" && name != laterBase {
+				if s := closestSection(known, current); s != "" {
+					current = s
+					n = headingNode(blackfriday.NewNode(blackfriday.Document), 2, s)
+				}
+			}
+			if _, ok := headings[current]; !ok {
+				headings[current] = n
+				order = append(order, current)
+			}
+			continue
+		}
+		switch {
+		case current != "":
+			content[current] = append(content[current], n)
+		case n.Type == blackfriday.List && isToday:
+			strays = append(strays, n)
+		default:
+			preface = append(preface, n)
+		}
+	}
+	if !date.IsZero() {
+		if len(preface) == 0 || preface[0].Type != blackfriday.Heading || preface[0].Level != 1 {
+			headingNode(doc, 1, date.Format("2006-01-02, Monday"))
+		}
+	}
+	for _, n := range preface {
+		doc.AppendChild(n)
+	}
+	if len(strays) > 0 {
+		if _, ok := headings["Inbox"]; !ok {
+			headings["Inbox"] = headingNode(blackfriday.NewNode(blackfriday.Document), 2, "Inbox")
+			order = append([]string{"Inbox"}, order...)
+		}
+		content["Inbox"] = append(content["Inbox"], strays...)
+	}
+	for _, s := range order {
+		doc.AppendChild(headings[s])
+		for _, n := range content[s] {
+			doc.AppendChild(n)
+		}
+	}
+}
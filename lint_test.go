@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+func TestLintFixDateHeading(t *testing.T) {
+	dir, err := ioutil.TempDir("", "today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	yesterday := day(time.Now()).AddDate(0, 0, -1)
+	for _, tc := range []struct {
+		file     string
+		modified time.Time
+		heading  string
+	}{
+		{filepath.Join("2026", "10", "16.today.md"), time.Now(), "2026-10-16, Friday"},
+		{"today.md", yesterday.Add(15 * time.Hour), yesterday.Format("2006-01-02, Monday")},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			file := filepath.Join(dir, tc.file)
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				t.Fatal(err)
+			}
+			b := []byte("## Inbox\n\n- [ ] Call mum\n")
+			if err := ioutil.WriteFile(file, b, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(file, tc.modified, tc.modified); err != nil {
+				t.Fatal(err)
+			}
+			tt, err := parse(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fixed := lintFix(file, tt, config{})
+			h := fixed.node.FirstChild
+			if h == nil || h.Type != blackfriday.Heading || h.Level != 1 || inlineText(h) != tc.heading {
+				t.Errorf("expected the heading '%s', got %v", tc.heading, h)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// groupTasks applies the filter, and groups in order of first appearance
+func groupTasks(f *taskFilter, ts []task) ([]string, map[string][]task) {
+	var (
+		order  = []string{}
+		groups = map[string][]task{}
+	)
+	for _, t := range ts {
+		if !f.match(t) {
+			continue
+		}
+		for _, g := range f.groups(t) {
+			if _, ok := groups[g]; !ok {
+				order = append(order, g)
+			}
+			groups[g] = append(groups[g], t)
+		}
+	}
+	return order, groups
+}
+
+// loadAllTasks loads the tasks from the today.md of every list, labelled with the list
+func loadAllTasks() ([]task, error) {
+	names, err := getListNames()
+	if err != nil {
+		return nil, err
+	}
+	ret := []task{}
+	for _, name := range names {
+		dir, err := getListDir(name)
+		if err != nil {
+			return nil, err
+		}
+		t, err := parseFile(filepath.Join(dir, todayBase))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ts := flatten(t.Tasks())
+		for i := range ts {
+			ts[i].List = name
+		}
+		ret = append(ret, ts...)
+	}
+	return ret, nil
+}
+
+// listName is the name of a list, or of the current one
+func listName(names ...string) string {
+	name := currentList
+	if len(names) > 0 {
+		name = names[0]
+	}
+	if name == "" {
+		return defaultList
+	}
+	return name
+}
+
+func printLists(args []string) error {
+	names, err := getListNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == listName() {
+			fmt.Println("*", name)
+		} else {
+			fmt.Println(" ", name)
+		}
+	}
+	return nil
+}
+
+// loadTasksArg loads tasks from the file given as the only argument, defaulting to today.md
+func loadTasksArg(fs *flag.FlagSet) ([]task, error) {
+	var (
+		t   tasks
+		err error
+	)
+	if fs.NArg() > 0 {
+		t, err = parseFile(fs.Arg(0))
+	} else {
+		t, err = loadToday()
+	}
+	if err != nil {
+		return nil, err
+	}
+	ts := flatten(t.Tasks())
+	for i := range ts {
+		ts[i].List = listName()
+	}
+	return ts, nil
+}
+
+func list(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	f := addFilterFlags(fs)
+	all := fs.Bool("all", false, "combine the tasks of every list, labelled with their list")
+	sortBy := fs.String("sort", "", "order each group: "+sortPriority+" (then age, by created:)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *sortBy != "" && *sortBy != sortPriority {
+		return fmt.Errorf("unknown sort '%s' (expected %s)", *sortBy, sortPriority)
+	}
+	if err := f.validate(); err != nil {
+		return err
+	}
+	var (
+		ts  []task
+		err error
+	)
+	if *all {
+		if fs.NArg() > 0 {
+			return fmt.Errorf("--all cannot be used with a file")
+		}
+		ts, err = loadAllTasks()
+	} else {
+		ts, err = loadTasksArg(fs)
+	}
+	if err != nil {
+		return err
+	}
+	order, groups := groupTasks(f, ts)
+	for i, g := range order {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("## %s\n", g)
+		if *sortBy == sortPriority {
+			sortTasks(groups[g])
+		}
+		for _, t := range groups[g] {
+			if *all && f.group != "list" {
+				fmt.Printf("- [%s] %s (%s)\n", t.Status, t.Description, t.List)
+				continue
+			}
+			fmt.Printf("- [%s] %s\n", t.Status, t.Description)
+		}
+	}
+	return nil
+}
+
+func stats(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	f := addFilterFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := f.validate(); err != nil {
+		return err
+	}
+	ts, err := loadTasksArg(fs)
+	if err != nil {
+		return err
+	}
+	keys := []string{}
+	for k := range statuses {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprint(w, f.group, "\tTotal")
+	for _, k := range keys {
+		fmt.Fprint(w, "\t", statuses[k])
+	}
+	fmt.Fprintln(w)
+	order, groups := groupTasks(f, ts)
+	for _, g := range order {
+		counts := map[string]int{}
+		for _, t := range groups[g] {
+			counts[t.Status]++
+		}
+		fmt.Fprintf(w, "%s\t%d", g, len(groups[g]))
+		for _, k := range keys {
+			fmt.Fprintf(w, "\t%d", counts[k])
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// search looks for tasks in today.md and every archive
+func search(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	f := addFilterFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := f.validate(); err != nil {
+		return err
+	}
+	query := strings.ToLower(strings.Join(fs.Args(), " "))
+	base, err := getBaseDir()
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(base, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path == filepath.Join(base, listsDir) {
+			// the named lists are searched with --list
+			return filepath.SkipDir
+		}
+		if info.IsDir() || !strings.HasSuffix(path, todayBase) {
+			return nil
+		}
+		t, err := parseFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		for _, tk := range flatten(t.Tasks()) {
+			if !f.match(tk) || !strings.Contains(strings.ToLower(tk.Description), query) {
+				continue
+			}
+			fmt.Printf("%s:%s: [%s] %s\n", rel, tk.Section, tk.Status, tk.Description)
+		}
+		return nil
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -17,8 +18,8 @@ import (
 
 const (
 	usage = `today
-Usage:
-	today init     - initialise todo directory with today.md (and recurring.md)	
+Usage: today [--verbose|--debug] [--list work] <subcommand>
+	today init [--force] [--template work] - initialise todo directory with today.md (and recurring.md, later.md)
 	today config   - print config variables 
 	today rollover - back up, prune completed/cancelled tasks and reset regular tasks
 	today rollover-dryrun - print rolledover file to stdout
@@ -27,6 +28,26 @@ Usage:
 	today days     - list a few days (for fzf inputs) 
 	today headings - list the headings in a file
 	today statuses - list the statuses
+	today move <task> --to <section> [--top] | --up | --down | --top | --bottom - move a task (with its subtasks). Subtasks move within their list
+	today defer <task> <date|someday> - move a task from today.md into later.md
+	today recurring next [-n 3] - preview the next occurrences of recurring tasks
+	today lint [--fix] [file...] - report (and repair) structural problems in today.md, recurring.md and later.md
+	today due-now [-within 15m] [-count] - list open tasks whose time (at:09:30) has come
+	today review --week|--month [yyyy-mm-dd] - compile a review of archived days
+	today list [filters] [--all] [--sort priority] [file] - list tasks, grouped by section/tag/context/project/status/list
+	today lists    - list the named lists
+	today standup [--format text|markdown|slack] - summarise yesterday's archive and today's plan
+	today export --format ics|org|taskwarrior|todotxt [-o file] - export tasks (and recurring tasks) for other tools
+	today import --format ics|org|taskwarrior|todotxt [--dryrun] [file...] - import tasks into the Inbox (and recurring ones into recurring.md)
+	today render [--format gfm|html] [-o file] [file] - write today.md with GFM checkboxes, for other markdown tools
+	today serve [--addr 127.0.0.1:8080] - serve a JSON API and HTML view of today.md and the archives
+	today tui      - edit today.md interactively
+	today watch [--dryrun] - tidy today.md after each save, and roll over at the end of the day
+	today team     - a standup report for the team configured in config.json
+	today stats [filters] [file] - count tasks by status for each group
+	today search [filters] <text> - search today.md and the archives
+
+Filters: --tag home --context phone --project garden --status x --group tag
 `
 )
 
@@ -36,10 +57,30 @@ var (
 )
 
 func main() {
-	args := os.Args[1:]
+	global := flag.NewFlagSet("today", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	verbose := global.Bool("verbose", false, "report what is being done, on stderr")
+	debug := global.Bool("debug", false, "report diagnostics, on stderr")
+	global.StringVar(&currentList, "list", "", "use a named list, e.g. work, with its own files")
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(1)
+	}
+	if currentList != "" {
+		if err := validListName(currentList); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *verbose {
+		verbosity = levelVerbose
+	}
+	if *debug {
+		verbosity = levelDebug
+	}
+	args := global.Args()
 	if len(args) < 1 {
 		fmt.Fprintln(os.Stderr, "Please specify a subcommand")
-		fmt.Fprintln(os.Stderr, usage)
+		fmt.Fprint(os.Stderr, usage)
 		os.Exit(1)
 	}
 	var (
@@ -65,6 +106,42 @@ func main() {
 		err = printHeadings(args)
 	case "statuses":
 		err = printStatuses(args)
+	case "move":
+		err = moveCmd(args)
+	case "defer":
+		err = deferTask(args)
+	case "recurring":
+		err = recurringCmd(args)
+	case "due-now":
+		err = dueNow(args)
+	case "lint":
+		err = lint(args)
+	case "review":
+		err = review(args)
+	case "list":
+		err = list(args)
+	case "standup":
+		err = standupCmd(args)
+	case "export":
+		err = exportCmd(args)
+	case "import":
+		err = importCmd(args)
+	case "render":
+		err = renderCmd(args)
+	case "serve":
+		err = serve(args)
+	case "watch":
+		err = watch(args)
+	case "tui":
+		err = tuiCmd(args)
+	case "team":
+		err = team(args)
+	case "lists":
+		err = printLists(args)
+	case "stats":
+		err = stats(args)
+	case "search":
+		err = search(args)
 	default:
 		err = errors.New("Unrecognised subcommand")
 		printUsage = true
@@ -72,7 +149,7 @@ func main() {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "[%s]: %v\n", args[0], err)
 		if printUsage {
-			fmt.Fprintln(os.Stderr, usage)
+			fmt.Fprint(os.Stderr, usage)
 		}
 		os.Exit(1)
 	}
@@ -83,7 +160,11 @@ func printConfig(args []string) error {
 	if err != nil {
 		return err
 	}
-	c, err := json.Marshal(map[string]interface{}{"base": baseDir, "today": filepath.Join(baseDir, todayBase), "recurring": filepath.Join(baseDir, recurringBase), "states": statuses})
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	c, err := json.Marshal(map[string]interface{}{"base": baseDir, "today": filepath.Join(baseDir, todayBase), "recurring": filepath.Join(baseDir, recurringBase), "later": filepath.Join(baseDir, laterBase), "config": filepath.Join(baseDir, configBase), "list": listName(), "template": filepath.Join(baseDir, templateBase), "states": statuses, "sections": cfg.layout()})
 	if err != nil {
 		return err
 	}
@@ -99,6 +180,22 @@ func loadToday() (tasks, error) {
 	return parseFile(file)
 }
 
+// loadLater loads later.md, or starts a new one
+func loadLater() (tasks, error) {
+	file, err := getLaterFilename()
+	if err != nil {
+		return tasks{}, err
+	}
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		doc := blackfriday.NewNode(blackfriday.Document)
+		headingNode(doc, 1, "Later")
+		headingNode(doc, 2, laterScheduled)
+		headingNode(doc, 2, laterSomeday)
+		return tasks{node: doc}, nil
+	}
+	return parseFile(file)
+}
+
 func loadRecurring() (tasks, error) {
 	file, err := getRecurringFilename()
 	if err != nil {
@@ -122,6 +219,7 @@ func backUpToday() error {
 	if err != nil {
 		return err
 	}
+	tasks.file = ft
 	h := tasks.GetFirstHeadingText()
 	var fa string
 	var archiveTime time.Time
@@ -131,7 +229,7 @@ func backUpToday() error {
 		}
 		t, err := time.Parse("2006-01-02", h)
 		if err != nil {
-			return err
+			return tasks.errorAt(1, checkDateHeading(h))
 		}
 		archiveTime = t
 	}
@@ -145,8 +243,31 @@ func backUpToday() error {
 	if err != nil {
 		return err
 	}
+	verbosef("archiving %s to %s", ft, fa)
+	return appendFile(fa, input)
+}
+
+// backUpRecurring archives recurring.md under today's date, before it is replaced
+func backUpRecurring() error {
+	fr, err := getRecurringFilename()
+	if err != nil {
+		return err
+	}
+	input, err := ioutil.ReadFile(fr)
+	if err != nil {
+		return err
+	}
+	fa, err := getRecurringArchiveFilename(time.Now())
+	if err != nil {
+		return err
+	}
+	verbosef("archiving %s to %s", fr, fa)
+	return appendFile(fa, input)
+}
+
+func appendFile(fa string, input []byte) error {
 	d := filepath.Dir(fa)
-	if err = os.MkdirAll(d, 0755); err != nil {
+	if err := os.MkdirAll(d, 0755); err != nil {
 		return err
 	}
 
@@ -225,54 +346,211 @@ func filterDone(nodes []*blackfriday.Node) []*blackfriday.Node {
 
 }
 
-func newToday(current tasks, recurring tasks, old tasks, rollInbox bool) error {
+func newToday(current tasks, recurring tasks, old tasks, later tasks, rollInbox bool) error {
 	f, err := getTodayFilename()
 	if err != nil {
 		return err
 	}
-	c, err := buildToday(current, recurring, old, rollInbox)
+	c, err := buildToday(current, recurring, old, later, rollInbox)
 	if err != nil {
 		return err
 	}
 	return newFile(f, c)
 }
 
-func buildToday(current tasks, recurring tasks, old tasks, rollInbox bool) (tasks, error) {
-	headingNode(current.node, 2, "Inbox") // empty
+// buildToday rolls over undone tasks from old into current, laid out by template.md or else section by section as configured.
+// Tasks scheduled for a future day are moved into later, and those now due are promoted from later into the Inbox
+func buildToday(current tasks, recurring tasks, old tasks, later tasks, rollInbox bool) (tasks, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return current, err
+	}
+	today := day(time.Now())
+	ref, ok := old.headingDate()
+	if !ok {
+		ref = today
+	}
+	// before done tasks are pruned
+	done, err := loadCompletions(recurring, today, old)
+	if err != nil {
+		return current, err
+	}
+	p := &dayParts{date: today, recurring: recurring, done: done, custom: map[string][]*blackfriday.Node{}}
+	overdue := []*blackfriday.Node{}
+	route := func(nodes []*blackfriday.Node) []*blackfriday.Node {
+		filtered, o := routeDated(filterDone(nodes), ref, today, later)
+		overdue = append(overdue, o...)
+		return filtered
+	}
+	policies := missedPolicies(recurring)
+	oldInbox, oldRolled, oldDaily := old.ByHeader("Inbox"), old.ByHeader("Rolled Over"), []*blackfriday.Node{}
+	if rollInbox {
+		// recurring tasks are only carried once, apart from overdue ones
+		oldInbox = dropCarried(oldInbox, policies)
+		oldRolled = dropCarried(oldRolled, policies)
+		oldDaily = carryMissed(old.ByHeader("Daily"), policies, ref)
+	}
+	oldOverdue := route(old.ByHeader("Overdue"))
+	oldInbox = route(oldInbox)
+	oldRolled = route(oldRolled)
+	oldDaily = route(oldDaily)
+
+	// custom sections
+	layout := cfg.layout()
+	oldCustom := []*blackfriday.Node{} // rolled into Rolled Over
+	for _, s := range layout {
+		if isBuiltinSection(s.Name) {
+			continue
+		}
+		nodes := old.Section(s.Name)
+		policy := s.policy()
+		if !rollInbox && policy != policyKeep {
+			// same day: just prune
+			policy = policySelf
+		}
+		switch policy {
+		case policyRolledOver:
+			oldCustom = append(oldCustom, route(nodes)...)
+		case policySelf:
+			p.custom[s.Name] = route(nodes)
+		case policyKeep:
+			p.custom[s.Name] = nodes
+		case policyTemplate:
+			if p.custom[s.Name], err = s.templateNodes(); err != nil {
+				return current, err
+			}
+		}
+		debugf("section '%s': %s", s.Name, policy)
+	}
 
+	if len(overdue) > 0 {
+		p.overdue = []*blackfriday.Node{newListNode()}
+		for _, item := range overdue {
+			p.overdue[0].AppendChild(item)
+		}
+	}
+	// the Inbox is empty, apart from anything scheduled for today
+	if promoted := promoteScheduled(later, today); len(promoted) > 0 {
+		list := newListNode()
+		for _, item := range promoted {
+			list.AppendChild(item)
+		}
+		p.inbox = append(p.inbox, list)
+	}
 	if rollInbox {
-		headingNode(current.node, 2, "Rolled Over")
-		//para := paraNode(current.node)
-	}
-	unfiltered := old.ByHeader("Inbox")
-	filtered := filterDone(unfiltered)
-	//log.Printf("unfiltered/filtered: %d/%d", len(unfiltered), len(filtered))
-	for _, f := range filtered {
-		current.node.AppendChild(f)
-	}
-	//current.node.SetChildren(append(current.node.GetChildren(), i...))
-
-	if !rollInbox {
-		headingNode(current.node, 2, "Rolled Over")
-		//para := paraNode(current.node)
-	}
-	unfiltered = old.ByHeader("Rolled Over")
-	filtered = filterDone(unfiltered)
-	//log.Printf("unfiltered/filtered: %d/%d", len(unfiltered), len(filtered))
-	for _, f := range filtered {
-		current.node.AppendChild(f)
-	}
-	//current.node.SetChildren(append(current.node.GetChildren(), i...))
-
-	headingNode(current.node, 2, "Daily")
-	// get recurring events
-	d := recurring.ByHeader("Daily")
-	//log.Printf("daily: %d", len(d))
-	for _, n := range d {
-		current.node.AppendChild(n)
-	}
-	//current.node.SetChildren(append(current.node.GetChildren(), t...))
-	return current, nil
+		p.rolled = append(p.rolled, oldInbox...)
+	} else {
+		p.inbox = append(p.inbox, oldInbox...)
+	}
+	// no longer overdue (the due date was changed)
+	p.rolled = append(p.rolled, oldOverdue...)
+	p.rolled = append(p.rolled, oldRolled...)
+	p.rolled = append(p.rolled, oldCustom...)
+	// missed recurring tasks
+	p.rolled = append(p.rolled, oldDaily...)
+	if cfg.Rollover.Sort == sortPriority {
+		p.inbox, p.rolled = sortItems(p.inbox, today), sortItems(p.rolled, today)
+	}
+
+	tmpl, err := loadTemplate()
+	if err != nil {
+		return current, err
+	}
+	if tmpl != nil {
+		verbosef("laying out today.md from %s", templateBase)
+		return p.execute(tmpl)
+	}
+	return p.layout(current, layout), nil
+}
+
+// layout adds the parts to current, section by section
+func (p *dayParts) layout(current tasks, layout []sectionConfig) tasks {
+	if len(p.overdue) > 0 {
+		headingNode(current.node, 2, "Overdue")
+		appendNodes(current.node, p.overdue)
+	}
+	var daily *blackfriday.Node
+	for _, s := range layout {
+		switch s.Name {
+		case "Inbox":
+			headingNode(current.node, 2, "Inbox")
+			appendNodes(current.node, p.inbox)
+		case "Rolled Over":
+			headingNode(current.node, 2, "Rolled Over")
+			appendNodes(current.node, p.rolled)
+		case "Daily":
+			daily = headingNode(current.node, 2, "Daily")
+		default:
+			headingNode(current.node, 2, s.Name)
+			appendNodes(current.node, p.custom[s.Name])
+		}
+	}
+	// get recurring events, unless they were carried over (into any section)
+	if d := p.recurringItems(); len(d) > 0 {
+		list := newListNode()
+		for _, n := range d {
+			list.AppendChild(n)
+		}
+		if daily.Next != nil {
+			daily.Next.InsertBefore(list)
+		} else {
+			current.node.AppendChild(list)
+		}
+	}
+	return current
+}
+
+func appendNodes(parent *blackfriday.Node, nodes []*blackfriday.Node) {
+	for _, n := range nodes {
+		parent.AppendChild(n)
+	}
+}
+
+// routeDated takes items out of the given lists: those scheduled after today are moved into later, and those due
+// before today are returned as overdue. Relative dates are rewritten, as they were relative to ref
+func routeDated(nodes []*blackfriday.Node, ref, today time.Time, later tasks) ([]*blackfriday.Node, []*blackfriday.Node) {
+	var (
+		remaining = []*blackfriday.Node{}
+		overdue   = []*blackfriday.Node{}
+	)
+	for _, n := range nodes {
+		if n.Type != blackfriday.List {
+			remaining = append(remaining, n)
+			continue
+		}
+		for item := n.FirstChild; item != nil; {
+			next := item.Next
+			normaliseDates(item, ref)
+			t, ok := parseTask(item, ref)
+			switch {
+			case !ok:
+			case t.Scheduled.After(today):
+				item.Unlink()
+				later.SectionList(laterScheduled).AppendChild(item)
+			case !t.Due.IsZero() && t.Due.Before(today):
+				item.Unlink()
+				overdue = append(overdue, item)
+			}
+			item = next
+		}
+		if n.FirstChild != nil {
+			remaining = append(remaining, n)
+		}
+	}
+	return remaining, overdue
+}
+
+// promoteScheduled takes tasks out of later, once their scheduled day has arrived
+func promoteScheduled(later tasks, today time.Time) []*blackfriday.Node {
+	promoted := []*blackfriday.Node{}
+	for _, t := range later.Tasks() {
+		if t.Scheduled.IsZero() || t.Scheduled.After(today) || t.IsClosed() {
+			continue
+		}
+		t.node.Unlink()
+		promoted = append(promoted, t.node)
+	}
+	return promoted
 }
 
 func newRecurring(recurring tasks) error {
@@ -283,12 +561,16 @@ func newRecurring(recurring tasks) error {
 	return newFile(f, recurring)
 }
 
-func newFile(filename string, t tasks) error {
-	d, err := getBaseDir()
+func newLater(later tasks) error {
+	f, err := getLaterFilename()
 	if err != nil {
 		return err
 	}
-	err = os.MkdirAll(d, 0755)
+	return newFile(f, later)
+}
+
+func newFile(filename string, t tasks) error {
+	err := os.MkdirAll(filepath.Dir(filename), 0755)
 	if err != nil {
 		return err
 	}
@@ -303,41 +585,45 @@ func newFile(filename string, t tasks) error {
 }
 
 func getDateHeader(t tasks) (time.Time, error) {
-	var d time.Time
+	var (
+		d      time.Time
+		reason error
+		line   = 1
+	)
 	t.node.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
 		switch node.Type {
 		case blackfriday.Heading:
-			if node.Level == 1 {
+			if node.Level == 1 && entering {
 				// first child is usually TextNode
 				if node.FirstChild == nil || node.FirstChild.Type != blackfriday.Text {
-					// skip: no text node
-					fmt.Printf("skip: first child doesn't match: %v\n", node.FirstChild)
+					debugf("skipping level 1 heading without text: %v", node.FirstChild)
 					return blackfriday.GoToNext
 				}
 				content := string(node.FirstChild.Literal)
-				if len(content) < 10 {
-					// skip: too short
-					fmt.Printf("skip: first child content too short: %v\n", content)
-					return blackfriday.GoToNext
-				}
-				if len(content) > 10 {
-					content = content[0:10]
-				}
-				var err error
-				d, err = time.Parse("2006-01-02", content)
-				if err != nil {
-					// skip
-					fmt.Printf("%s doesn't match: %v\n", content, err)
+				if err := checkDateHeading(content); err != nil {
+					debugf("skipping level 1 heading: %v", err)
+					if reason == nil {
+						reason = err
+						line = t.lineOf(func(l string) bool {
+							m := headingLine.FindStringSubmatch(l)
+							return m != nil && len(m[1]) == 1 && m[2] == content
+						})
+					}
 					return blackfriday.GoToNext
 				}
+				d, _ = time.ParseInLocation("2006-01-02", content[:10], time.Local)
 				return blackfriday.Terminate
 			}
 		}
 		return blackfriday.GoToNext
 	})
 	if d.IsZero() {
-		return d, errors.New("Not found")
+		if reason == nil {
+			reason = fmt.Errorf("no date heading (e.g. '# %s')", time.Now().Format("2006-01-02, Monday"))
+		}
+		return d, t.errorAt(line, reason)
 	}
+	debugf("date heading: %s", d.Format("2006-01-02"))
 	return d, nil
 }
 
@@ -359,6 +645,7 @@ func prune(args []string, mustRollover bool, dryRun bool) error {
 	if mustRollover && !rollInbox {
 		return errors.New("same day - no rollover")
 	}
+	verbosef("today.md is dated %s, rollover: %v", d.Format("2006-01-02"), rollInbox)
 	if !dryRun {
 		if err := backUpToday(); err != nil {
 			return err
@@ -369,8 +656,10 @@ func prune(args []string, mustRollover bool, dryRun bool) error {
 	doc := blackfriday.NewNode(blackfriday.Document)
 	today := tasks{node: doc}
 	todayNode(today.node)
-	//fmt.Println("Before:")
-	//printAST(os.Stdout, old.node)
+	if verbosity >= levelDebug {
+		debugf("before:")
+		printAST(os.Stderr, old.node)
+	}
 	r := markdown.NewRenderer(&markdown.Options{Terminal: false, HashHeaders: true})
 	//render(r, os.Stdout, old.node)
 
@@ -379,7 +668,11 @@ func prune(args []string, mustRollover bool, dryRun bool) error {
 	if err != nil {
 		return err
 	}
-	c, err := buildToday(today, recurring, old, rollInbox)
+	later, err := loadLater()
+	if err != nil {
+		return err
+	}
+	c, err := buildToday(today, recurring, old, later, rollInbox)
 	if err != nil {
 		return err
 
@@ -387,15 +680,21 @@ func prune(args []string, mustRollover bool, dryRun bool) error {
 	_ = c
 	_ = r
 	if dryRun {
-		//fmt.Println("\nAfter:")
-		//printAST(os.Stdout, c.node)
+		if verbosity >= levelDebug {
+			debugf("after:")
+			printAST(os.Stderr, c.node)
+		}
 		render(r, os.Stdout, c.node)
 	} else {
 		f, err := getTodayFilename()
 		if err != nil {
 			return err
 		}
-		return newFile(f, c)
+		if err := newFile(f, c); err != nil {
+			return err
+		}
+		verbosef("wrote %s", f)
+		return newLater(later)
 	}
 	return nil
 }
@@ -451,12 +750,20 @@ func printHeadings(args []string) error {
 	return nil
 }
 
-const (
-	force             = true
-	forceNewRecurring = false
-)
-
 func initialise(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	force := fs.Bool("force", false, "start again, archiving the existing files first")
+	starter := fs.String("template", "", "seed recurring.md from a starter set: "+strings.Join(starterNames(), ", "))
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	var seed string
+	if *starter != "" {
+		var ok bool
+		if seed, ok = starters[*starter]; !ok {
+			return fmt.Errorf("no starter set '%s' (try one of %s)", *starter, strings.Join(starterNames(), ", "))
+		}
+	}
 	tf, err := getTodayFilename()
 	if err != nil {
 		return err
@@ -465,18 +772,43 @@ func initialise(args []string) error {
 	if err != nil {
 		return err
 	}
-	recurringExists := false
-	if _, err := os.Stat(rf); !os.IsNotExist(err) {
-		recurringExists = true
+	todayExists, recurringExists := exists(tf), exists(rf)
+	// refuse before anything is written
+	if !*force {
+		if todayExists {
+			return fmt.Errorf("%s already exists (use --force to archive it and start a new day)", tf)
+		}
+		if recurringExists && seed != "" {
+			return fmt.Errorf("%s already exists (use --force to archive it and replace it)", rf)
+		}
 	}
 
 	doc := blackfriday.NewNode(blackfriday.Document)
 	today := tasks{node: doc}
 	todayNode(today.node)
 
-	doc = blackfriday.NewNode(blackfriday.Document)
-	recurring := tasks{node: doc}
-	if !recurringExists || forceNewRecurring {
+	var recurring tasks
+	switch {
+	case seed != "":
+		if recurringExists {
+			if err := backUpRecurring(); err != nil {
+				return err
+			}
+		}
+		if recurring, err = parse([]byte(seed)); err != nil {
+			return err
+		}
+		if err := newRecurring(recurring); err != nil {
+			return err
+		}
+		verbosef("wrote %s from the '%s' starter set", rf, *starter)
+		// parsed again, as rollover takes items from it
+		if recurring, err = parseFile(rf); err != nil {
+			return err
+		}
+	case !recurringExists:
+		doc = blackfriday.NewNode(blackfriday.Document)
+		recurring = tasks{node: doc}
 		recurring.node.AppendChild(headingNode(recurring.node, 1, "Recurring tasks"))
 		recurring.node.AppendChild(headingNode(recurring.node, 2, "Daily"))
 		recurring.node.AppendChild(headingNode(recurring.node, 2, "Weekly"))
@@ -485,23 +817,35 @@ func initialise(args []string) error {
 		if err != nil {
 			return err
 		}
-	} else {
+	default:
 		recurring, err = parseFile(rf)
 		if err != nil {
 			return err
 		}
 	}
 
-	if _, err := os.Stat(tf); os.IsNotExist(err) || force {
-		doc := blackfriday.NewNode(blackfriday.Document)
-		err = newToday(today, recurring, tasks{node: doc}, false) // nothing rolled over
-		if err != nil {
+	if todayExists {
+		// only with --force
+		if err := backUpToday(); err != nil {
 			return err
 		}
-	} else {
 	}
+	later, err := loadLater()
+	if err != nil {
+		return err
+	}
+	err = newToday(today, recurring, tasks{node: blackfriday.NewNode(blackfriday.Document)}, later, false) // nothing rolled over
+	if err != nil {
+		return err
+	}
+	verbosef("wrote %s", tf)
+	// anything due today was promoted
+	return newLater(later)
+}
 
-	return nil
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return !os.IsNotExist(err)
 }
 
 func days(args []string) error {
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// moveCmd moves a task in today.md, with its subtasks, to another section or within its list. Subtasks only move within their list
+func moveCmd(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	to := fs.String("to", "", "move the task to the end of this section")
+	up := fs.Bool("up", false, "move the task up one place")
+	down := fs.Bool("down", false, "move the task down one place")
+	top := fs.Bool("top", false, "move the task to the top of its list (or of the --to section)")
+	bottom := fs.Bool("bottom", false, "move the task to the bottom of its list")
+	// flags may follow the selector, e.g. `today move "write report" --to "Rolled Over"`
+	words, rest := []string{}, args[1:]
+	for {
+		if err := fs.Parse(rest); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		words = append(words, fs.Arg(0))
+		rest = fs.Args()[1:]
+	}
+	sel := strings.Join(words, " ")
+	if sel == "" {
+		return errors.New("usage: today move <task> --to <section> [--top] | --up | --down | --top | --bottom")
+	}
+	moves := 0
+	for _, b := range []bool{*to != "", *up, *down, *top && *to == "", *bottom} {
+		if b {
+			moves++
+		}
+	}
+	if moves != 1 {
+		return errors.New("specify one of --to, --up, --down, --top or --bottom")
+	}
+	today, err := loadToday()
+	if err != nil {
+		return err
+	}
+	t, err := selectTask(flatten(today.Tasks()), sel)
+	if err != nil {
+		return err
+	}
+	item := t.node
+	where := ""
+	switch {
+	case *to != "":
+		if list := item.Parent; list != nil && list.Parent != nil && list.Parent.Type == blackfriday.Item {
+			// it would silently leave its parent
+			return fmt.Errorf("'%s' is a subtask, so it can only move within its list (--up, --down, --top or --bottom)", t.Description)
+		}
+		section, err := resolveSection(today, *to)
+		if err != nil {
+			return err
+		}
+		today.moveToSection(item, section)
+		where = "to " + section
+		if *top {
+			if item.Prev != nil {
+				first := item.Parent.FirstChild
+				item.Unlink()
+				first.InsertBefore(item)
+			}
+			where = "to the top of " + section
+		}
+	case *up, *down:
+		if !shiftItem(item, *up) {
+			return fmt.Errorf("'%s' is already at the %s of its list", t.Description, map[bool]string{true: "top", false: "bottom"}[*up])
+		}
+		where = map[bool]string{true: "up", false: "down"}[*up]
+	case *top:
+		if item.Prev != nil {
+			first := item.Parent.FirstChild
+			item.Unlink()
+			first.InsertBefore(item)
+		}
+		where = "to the top"
+	case *bottom:
+		if item.Next != nil {
+			list := item.Parent
+			item.Unlink()
+			list.AppendChild(item)
+		}
+		where = "to the bottom"
+	}
+	if err := newFile(today.file, today); err != nil {
+		return err
+	}
+	fmt.Printf("Moved '%s' %s\n", t.Description, where)
+	return nil
+}
+
+// resolveSection finds a section of today.md, or a configured one, by name. Case is ignored
+func resolveSection(t tasks, name string) (string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	known := cfg.sectionNames()
+	for n := t.node.FirstChild; n != nil; n = n.Next {
+		if n.Type == blackfriday.Heading && n.Level == 2 && !containsFold(known, inlineText(n)) {
+			known = append(known, inlineText(n))
+		}
+	}
+	for _, k := range known {
+		if strings.EqualFold(k, name) {
+			return k, nil
+		}
+	}
+	if s := closestSection(known, name); s != "" {
+		return "", fmt.Errorf("unknown section '%s' (did you mean '%s'?)", name, s)
+	}
+	return "", fmt.Errorf("unknown section '%s' (expected one of %s)", name, strings.Join(known, ", "))
+}
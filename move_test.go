@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const moveToday = `# 2026-10-19, Monday
+
+## Inbox
+
+- [ ] Call mum
+- [ ] Write report
+	- [ ] Outline
+	- [ ] Draft
+
+## Rolled Over
+
+- [ ] Pay rent
+`
+
+func TestMoveCmd(t *testing.T) {
+	home, err := ioutil.TempDir("", "today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+	file := filepath.Join(home, todayDir, todayBase)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		expected string // today.md afterwards, or else the error
+	}{
+		{"to a section, with its subtasks", []string{"Write report", "--to", "rolled over"}, `# 2026-10-19, Monday
+
+## Inbox
+
+-	[ ] Call mum
+
+## Rolled Over
+
+-	[ ] Pay rent
+-	[ ] Write report
+	-	[ ] Outline
+	-	[ ] Draft
+`},
+		{"to the top of a section", []string{"Write report", "--to", "Rolled Over", "--top"}, `# 2026-10-19, Monday
+
+## Inbox
+
+-	[ ] Call mum
+
+## Rolled Over
+
+-	[ ] Write report
+	-	[ ] Outline
+	-	[ ] Draft
+-	[ ] Pay rent
+`},
+		{"down", []string{"Call mum", "--down"}, `# 2026-10-19, Monday
+
+## Inbox
+
+-	[ ] Write report
+	-	[ ] Outline
+	-	[ ] Draft
+-	[ ] Call mum
+
+## Rolled Over
+
+-	[ ] Pay rent
+`},
+		{"a subtask within its list", []string{"Draft", "--top"}, `# 2026-10-19, Monday
+
+## Inbox
+
+-	[ ] Call mum
+-	[ ] Write report
+	-	[ ] Draft
+	-	[ ] Outline
+
+## Rolled Over
+
+-	[ ] Pay rent
+`},
+		{"up at the top", []string{"Call mum", "--up"}, "'Call mum' is already at the top of its list"},
+		{"a subtask to a section", []string{"Draft", "--to", "Rolled Over"}, "'Draft' is a subtask"},
+		{"to an unknown section", []string{"Call mum", "--to", "Rolled"}, "unknown section 'Rolled'"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(file, []byte(moveToday), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := moveCmd(append([]string{"move"}, tc.args...))
+			b, rerr := ioutil.ReadFile(file)
+			if rerr != nil {
+				t.Fatal(rerr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tc.expected) {
+					t.Errorf("error is '%v', expected '%s'", err, tc.expected)
+				}
+				if string(b) != moveToday {
+					t.Errorf("today.md changed after an error:\n%s", b)
+				}
+				return
+			}
+			if string(b) != tc.expected {
+				t.Errorf("today.md is:\n%s\nexpected:\n%s", b, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// orgStatuses maps statuses to Org-mode TODO keywords, as declared by orgTodoLine.
+// Others are written as TODO, with a TODAY_STATUS property so that they survive a round trip
+var orgStatuses = map[string]string{
+	" ": "TODO",
+	"i": "STARTED",
+	"p": "POSTPONED",
+	"x": "DONE",
+	"c": "CANCELLED",
+}
+
+const orgTodoLine = "#+TODO: TODO STARTED POSTPONED | DONE CANCELLED"
+
+var (
+	orgHeadline = regexp.MustCompile(`^(\*+)\s+(.*?)\s*$`)
+	orgTags     = regexp.MustCompile(`\s+(:[^\s:]+(?::[^\s:]+)*:)$`)
+	orgPriority = regexp.MustCompile(`^\[#([A-Z])\]\s*`)
+	// orgTimestamp is e.g. `SCHEDULED: <2026-10-20 Tue 09:30 .+1w>` or `CLOSED: [2026-10-19 Mon 17:02]`
+	orgTimestamp = regexp.MustCompile(`(SCHEDULED|DEADLINE|CLOSED):\s*[<\[](\d{4}-\d{2}-\d{2})(?:\s+[^\s\d>\]]+)?(?:\s+(\d{1,2}:\d{2})(?:-\d{1,2}:\d{2})?)?(?:\s+(\.\+|\+\+|\+)(\d+[dwmy]))?[^>\]]*[>\]]`)
+	orgProperty  = regexp.MustCompile(`^\s*:([^:\s]+):\s*(.*?)\s*$`)
+	orgTagChars  = regexp.MustCompile(`[^\w@#%]`)
+)
+
+// writeOrg writes a level 1 headline per section, with its tasks as level 2 TODO headlines
+func writeOrg(w io.Writer, ts []exportTask) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("#+TITLE: " + listName() + "\n")
+	bw.WriteString(orgTodoLine + "\n")
+	order, sections := []string{}, map[string][]exportTask{}
+	for _, t := range ts {
+		if _, ok := sections[t.Section]; !ok {
+			order = append(order, t.Section)
+		}
+		sections[t.Section] = append(sections[t.Section], t)
+	}
+	for _, s := range order {
+		bw.WriteString("\n* " + s + "\n")
+		for _, t := range sections[s] {
+			keyword, ok := orgStatuses[t.Status]
+			if !ok {
+				keyword = "TODO"
+			}
+			head := "** " + keyword + " "
+			if p := priorityLetter(t.Priority); p != "" {
+				head += "[#" + p + "] "
+			}
+			head += plainDescription(t.Description)
+			if len(t.Tags) > 0 {
+				tags := []string{}
+				for _, tag := range t.Tags {
+					tags = append(tags, orgTagChars.ReplaceAllString(tag, "_"))
+				}
+				head += " :" + strings.Join(tags, ":") + ":"
+			}
+			bw.WriteString(head + "\n")
+			if planning := orgPlanning(t); planning != "" {
+				bw.WriteString("   " + planning + "\n")
+			}
+			bw.WriteString("   :PROPERTIES:\n")
+			bw.WriteString("   :ID: " + t.uid + "\n")
+			if !t.Created.IsZero() {
+				bw.WriteString("   :CREATED: " + orgStamp('[', t.Created, false, "") + "\n")
+			}
+			if !ok {
+				bw.WriteString("   :TODAY_STATUS: " + t.Status + "\n")
+			}
+			bw.WriteString("   :END:\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// orgPlanning is the line of CLOSED, SCHEDULED and DEADLINE timestamps under a headline.
+// Recurring tasks are scheduled from their start, with a repeater
+func orgPlanning(t exportTask) string {
+	parts := []string{}
+	if !t.Completed.IsZero() && t.IsClosed() {
+		parts = append(parts, "CLOSED: "+orgStamp('[', t.Completed, false, ""))
+	}
+	switch r := t.Recurrence; {
+	case r != nil:
+		repeater, ok := orgRepeater(r)
+		if !ok {
+			verbosef("'%s' is exported without its repeater, as Org can't express %s", t.Description, r)
+		}
+		start := t.from
+		if next := r.next(time.Now(), t.from, t.until, 1); len(next) > 0 {
+			start = next[0]
+		}
+		timed := len(r.Times) > 0
+		if timed {
+			start = start.Add(time.Duration(r.Times[0]) * time.Minute)
+		}
+		parts = append(parts, "SCHEDULED: "+orgStamp('<', start, timed, repeater))
+	case !t.Scheduled.IsZero():
+		parts = append(parts, "SCHEDULED: "+orgStamp('<', t.Scheduled, false, ""))
+	}
+	switch {
+	case !t.Due.IsZero() && !t.At.IsZero():
+		d := time.Date(t.Due.Year(), t.Due.Month(), t.Due.Day(), t.At.Hour(), t.At.Minute(), 0, 0, time.Local)
+		parts = append(parts, "DEADLINE: "+orgStamp('<', d, true, ""))
+	case !t.Due.IsZero():
+		parts = append(parts, "DEADLINE: "+orgStamp('<', t.Due, false, ""))
+	case !t.At.IsZero() && t.Recurrence == nil:
+		parts = append(parts, "DEADLINE: "+orgStamp('<', t.At, true, ""))
+	}
+	return strings.Join(parts, " ")
+}
+
+// orgRepeater is a rule as a repeater such as +1w, or .+3d after being done.
+// A single weekday, day of the month or month is kept by starting on it
+func orgRepeater(r *recurrence) (string, bool) {
+	if len(r.ByDay)+len(r.ByMonthDay)+len(r.ByMonth) > 1 || len(r.Times) > 1 {
+		return "", false
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 || r.Freq != weekly {
+			return "", false
+		}
+	}
+	for _, d := range r.ByMonthDay {
+		if d < 0 {
+			return "", false
+		}
+	}
+	rec, ok := todoTxtRec(&recurrence{Freq: r.Freq, Interval: r.Interval, AfterDone: r.AfterDone})
+	if ok && r.AfterDone {
+		rec = ".+" + rec
+	}
+	return rec, ok
+}
+
+// orgStamp writes an active (<) or inactive ([) timestamp
+func orgStamp(open byte, d time.Time, timed bool, repeater string) string {
+	s := d.Format("2006-01-02 Mon")
+	if timed {
+		s += d.Format(" 15:04")
+	}
+	if repeater != "" {
+		s += " " + repeater
+	}
+	if open == '[' {
+		return "[" + s + "]"
+	}
+	return "<" + s + ">"
+}
+
+// readOrg reads TODO headlines at any level. Headlines without a TODO keyword are taken to be sections
+func readOrg(r io.Reader) ([]task, error) {
+	var (
+		sc       = bufio.NewScanner(r)
+		ret      = []task{}
+		t        *task
+		keywords = map[string]string{}
+		n        = 0
+		drawer   = false
+	)
+	for k, s := range orgStatuses {
+		keywords[s] = k
+	}
+	finish := func() {
+		if t != nil && t.Description != "" {
+			if t.Recurrence != nil {
+				// the start of a recurring task is where its rule begins, rather than a scheduled date
+				t.From, t.Scheduled = t.Scheduled, time.Time{}
+			}
+			ret = append(ret, *t)
+		}
+		t = nil
+	}
+	for sc.Scan() {
+		n++
+		line := sc.Text()
+		if strings.HasPrefix(line, "#+TODO:") || strings.HasPrefix(line, "#+SEQ_TODO:") || strings.HasPrefix(line, "#+TYP_TODO:") {
+			// other keywords count as todo before the |, and as done after it
+			status := " "
+			for _, w := range strings.Fields(line[strings.IndexByte(line, ':')+1:]) {
+				if w == "|" {
+					status = "x"
+					continue
+				}
+				w = strings.SplitN(w, "(", 2)[0]
+				if _, ok := keywords[w]; !ok {
+					keywords[w] = status
+				}
+			}
+			continue
+		}
+		if m := orgHeadline.FindStringSubmatch(line); m != nil {
+			finish()
+			drawer = false
+			words := strings.SplitN(m[2], " ", 2)
+			status, ok := keywords[words[0]]
+			if !ok {
+				continue
+			}
+			t = &task{Status: status}
+			text := ""
+			if len(words) > 1 {
+				text = strings.TrimSpace(words[1])
+			}
+			if tm := orgTags.FindStringSubmatch(text); tm != nil {
+				t.Tags = strings.Split(strings.Trim(tm[1], ":"), ":")
+				text = strings.TrimSpace(text[:len(text)-len(tm[0])])
+			}
+			if pm := orgPriority.FindStringSubmatch(text); pm != nil {
+				t.Priority, _ = parsePriority("(" + pm[1] + ")")
+				text = text[len(pm[0]):]
+			}
+			t.Description = strings.Join(strings.Fields(text), " ")
+			continue
+		}
+		if t == nil {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == ":PROPERTIES:":
+			drawer = true
+			continue
+		case trimmed == ":END:":
+			drawer = false
+			continue
+		case drawer:
+			m := orgProperty.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			switch strings.ToUpper(m[1]) {
+			case "CREATED":
+				// e.g. [2026-10-19 Mon 09:12]
+				if f := strings.Fields(strings.Trim(m[2], "[]<>")); len(f) > 0 {
+					if d, err := time.ParseInLocation("2006-01-02", f[0], time.Local); err == nil {
+						t.Created = d
+					}
+				}
+			case "TODAY_STATUS":
+				if k, ok := statusKey(m[2]); ok {
+					t.Status = k
+				}
+			}
+			continue
+		}
+		for _, m := range orgTimestamp.FindAllStringSubmatch(line, -1) {
+			d, err := time.ParseInLocation("2006-01-02", m[2], time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			switch m[1] {
+			case "CLOSED":
+				t.Completed = d
+			case "SCHEDULED":
+				t.Scheduled = d
+			case "DEADLINE":
+				t.Due = d
+			}
+			if m[3] != "" && m[1] != "CLOSED" {
+				if at, ok := parseClock(m[3]); ok {
+					t.At = d.Add(time.Duration(at) * time.Minute)
+				}
+			}
+			if m[5] != "" && m[1] == "SCHEDULED" {
+				rec := m[5]
+				if m[4] != ".+" {
+					rec = "+" + rec
+				}
+				rule, err := parseTodoRec(rec)
+				if err != nil {
+					verbosef("line %d: %v, so it is imported as a one off", n, err)
+					continue
+				}
+				t.Recurrence = rule
+			}
+		}
+	}
+	finish()
+	return ret, sc.Err()
+}
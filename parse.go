@@ -11,7 +11,9 @@ func parseFile(file string) (tasks, error) {
 	if err != nil {
 		return tasks{}, err
 	}
-	return parse(b)
+	t, err := parse(b)
+	t.file = file
+	return t, err
 }
 
 func parse(b []byte) (tasks, error) {
@@ -29,5 +31,6 @@ func parse(b []byte) (tasks, error) {
 	md := blackfriday.New(blackfriday.WithExtensions(extensions), blackfriday.WithExtensions(blackfriday.CommonExtensions))
 
 	node := md.Parse(b)
-	return tasks{node: node}, nil
+	repairMarkers(node)
+	return tasks{node: node, src: b}, nil
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// sortPriority orders tasks by priority, then age
+const sortPriority = "priority"
+
+// parsePriority reads a priority marker: `(A)` as in todo.txt, `p1` to `p4`, or `!!!` (high) to `!` (low). 1 is the highest
+func parsePriority(word string) (int, bool) {
+	switch {
+	case len(word) == 3 && word[0] == '(' && word[2] == ')' && word[1] >= 'A' && word[1] <= 'Z':
+		return int(word[1]-'A') + 1, true
+	case len(word) == 2 && (word[0] == 'p' || word[0] == 'P') && word[1] >= '1' && word[1] <= '4':
+		n, _ := strconv.Atoi(word[1:])
+		return n, true
+	case word == "!!!", word == "!!", word == "!":
+		return 4 - len(word), true
+	}
+	return 0, false
+}
+
+// splitPriority finds a priority marker at the start of a description, or at its end, before any tags, dates and recurrence.
+// Markers elsewhere are words, as in "Call back re: p2 ticket", and a trailing `!` is punctuation, as in "Ship it !".
+// The description is returned without the marker, or its recurrence
+func splitPriority(desc string) (int, string) {
+	words := strings.Fields(stripRecurrenceText(desc))
+	if len(words) == 0 {
+		return 0, ""
+	}
+	if p, ok := parsePriority(words[0]); ok {
+		return p, strings.Join(words[1:], " ")
+	}
+	last, afterEmoji := -1, false
+	for i, w := range words {
+		switch {
+		case afterEmoji:
+			afterEmoji = false
+		case w == dueEmoji, w == scheduledEmoji:
+			afterEmoji = true
+		case !isMetadataWord(w):
+			last = i
+		}
+	}
+	if last < 1 || strings.HasPrefix(words[last], "!") {
+		return 0, strings.Join(words, " ")
+	}
+	p, ok := parsePriority(words[last])
+	if !ok {
+		return 0, strings.Join(words, " ")
+	}
+	return p, strings.Join(append(words[:last:last], words[last+1:]...), " ")
+}
+
+// isMetadataWord reports whether a word is a tag, context or project, or a token such as `due:fri` or `📅2026-10-20`
+func isMetadataWord(w string) bool {
+	if len(w) > 1 && strings.ContainsRune("#@+", rune(w[0])) {
+		return true
+	}
+	if strings.HasPrefix(w, dueEmoji) || strings.HasPrefix(w, scheduledEmoji) {
+		return true
+	}
+	i := strings.Index(w, ":")
+	return i > 0 && i < len(w)-1 && strings.ToLower(w[:i]) == w[:i]
+}
+
+// priorityLetter is a priority as in todo.txt, e.g. A for 1
+func priorityLetter(p int) string {
+	if p < 1 || p > 26 {
+		return ""
+	}
+	return string(rune('A' + p - 1))
+}
+
+// byPriority reports whether a comes before b: higher priorities first, then those without one.
+// Ties go to the older task, where both have a created: date, and then to one with a date. As sorts are stable,
+// other tasks keep their order in the file
+func byPriority(a, b task) bool {
+	pa, pb := a.Priority, b.Priority
+	if pa == 0 {
+		pa = 27
+	}
+	if pb == 0 {
+		pb = 27
+	}
+	if pa != pb {
+		return pa < pb
+	}
+	if !a.Created.IsZero() && !b.Created.IsZero() {
+		return a.Created.Before(b.Created)
+	}
+	return !a.Created.IsZero() && b.Created.IsZero()
+}
+
+func sortTasks(ts []task) {
+	sort.SliceStable(ts, func(i, j int) bool { return byPriority(ts[i], ts[j]) })
+}
+
+// sortItems sorts the items of a section by priority, then age, gathering its lists into one.
+// Subtasks move with their parent. Other nodes, such as notes, stay ahead of the list
+func sortItems(nodes []*blackfriday.Node, ref time.Time) []*blackfriday.Node {
+	var (
+		ret   = []*blackfriday.Node{}
+		items = []task{}
+	)
+	for _, n := range nodes {
+		if n.Type != blackfriday.List {
+			ret = append(ret, n)
+			continue
+		}
+		for item := n.FirstChild; item != nil; item = item.Next {
+			t, ok := parseTask(item, ref)
+			if !ok {
+				t = task{node: item}
+			}
+			items = append(items, t)
+		}
+	}
+	if len(items) == 0 {
+		return ret
+	}
+	sortTasks(items)
+	list := newListNode()
+	for _, t := range items {
+		list.AppendChild(t.node)
+	}
+	return append(ret, list)
+}
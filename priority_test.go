@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestSplitPriority(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		priority int
+		rest     string
+	}{
+		{"(A) Call mum", 1, "Call mum"},
+		{"p2 Call mum", 2, "Call mum"},
+		{"!!! Fix the build", 1, "Fix the build"},
+		{"! Water the plants", 3, "Water the plants"},
+		{"Pay the rent p1", 1, "Pay the rent"},
+		{"Pay the rent (B) #home due:2026-10-20", 2, "Pay the rent #home due:2026-10-20"},
+		{"Pay the rent p3 📅 2026-10-20", 3, "Pay the rent 📅 2026-10-20"},
+		{"Water the plants p2 every: 2 days", 2, "Water the plants"},
+		{"Call back re: p2 ticket", 0, "Call back re: p2 ticket"},
+		{"Ship it !", 0, "Ship it !"},
+		{"Ship it !!! #work", 0, "Ship it !!! #work"},
+		{"Fix (A) and (B) before p1", 1, "Fix (A) and (B) before"},
+		{"p1", 1, ""},
+		{"Read about the P4 engine", 0, "Read about the P4 engine"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			p, rest := splitPriority(tc.desc)
+			if p != tc.priority {
+				t.Errorf("priority is %d, expected %d", p, tc.priority)
+			}
+			if rest != tc.rest {
+				t.Errorf("description is '%s', expected '%s'", rest, tc.rest)
+			}
+		})
+	}
+}
+
+func TestParseTaskPriority(t *testing.T) {
+	tt, err := parse([]byte("# 2026-10-19, Monday\n\n## Inbox\n\n- [ ] Call back re: p2 ticket\n- [ ] Ship it !\n- [ ] (B) Call mum\n- [ ] Pay the rent p1 #home\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"Call back re: p2 ticket": 0, "Ship it !": 0, "(B) Call mum": 2, "Pay the rent p1 #home": 1}
+	ts := tt.Tasks()
+	if len(ts) != len(expected) {
+		t.Fatalf("%d tasks, expected %d", len(ts), len(expected))
+	}
+	for _, task := range ts {
+		if p, ok := expected[task.Description]; !ok || task.Priority != p {
+			t.Errorf("'%s' has priority %d, expected %d", task.Description, task.Priority, p)
+		}
+	}
+}
@@ -0,0 +1,1016 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+const (
+	monthly recurType = "monthly"
+	yearly  recurType = "yearly"
+)
+
+// recurrence is a subset of an iCalendar RRULE (RFC 5545)
+type recurrence struct {
+	Freq       recurType
+	Interval   int
+	ByDay      []nthWeekday
+	ByMonthDay []int // negative counts back from the end of the month
+	ByMonth    []time.Month
+	Times      []int // minutes after midnight, for tasks due at a time of day
+	AfterDone  bool  // the interval counts from when the task was last done, rather than on a calendar
+}
+
+// nthWeekday is e.g. the 2nd Tuesday (N=2), the last Friday (N=-1), or every Monday (N=0)
+type nthWeekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+var (
+	// recurrenceAnchor is used for intervals when a task has no `from:` date. It is a Monday
+	recurrenceAnchor = time.Date(1970, 1, 5, 0, 0, 0, 0, time.Local)
+
+	everyRegexp = regexp.MustCompile(`(?i)\bevery:\s*`)
+	// bareEvery is `every` without the colon, which is only a rule in recurring.md when a duration or time range follows,
+	// e.g. `every 2h from 09:00 to 17:00`
+	bareEvery    = regexp.MustCompile(`(?i)\bevery\s+(\d+[hdwmy]|\d+\s+(?:hour|day|week|month|year)s?|from\s+\d{1,2}:\d\d)\b`)
+	everyWord    = regexp.MustCompile(`(?i)\bevery\b`)
+	rruleRegexp  = regexp.MustCompile(`(?i)\s*\brrule:(\S+)`)
+	boundsRegexp = regexp.MustCompile(`\s*\b(from|until):(\S+)`)
+	compactUnit  = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+	clockRegexp  = regexp.MustCompile(`^([01]?\d|2[0-3]):([0-5]\d)$`)
+	atRegexp     = regexp.MustCompile(`\s*\bat:(\S+)`)
+	ifMissed     = regexp.MustCompile(`\s*\bif-missed:(\S*)`)
+	afterDone    = regexp.MustCompile(`(?i)\s*\bafter-done:\s*(\d+)([dwm])\b`)
+	ordinal      = regexp.MustCompile(`^(\d+)(st|nd|rd|th)$`)
+	monthDay     = regexp.MustCompile(`^(\d\d)-(\d\d)$`)
+
+	mondayToFriday = []nthWeekday{{Weekday: time.Monday}, {Weekday: time.Tuesday}, {Weekday: time.Wednesday}, {Weekday: time.Thursday}, {Weekday: time.Friday}}
+
+	rruleDays = map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+)
+
+// sectionRecurrence is the rule for tasks in recurring.md which don't have their own
+func sectionRecurrence(section string) *recurrence {
+	switch {
+	case strings.Contains(section, "Daily"):
+		return &recurrence{Freq: daily, Interval: 1}
+	case strings.Contains(section, "Weekdays"):
+		return &recurrence{Freq: weekly, Interval: 1, ByDay: mondayToFriday}
+	case strings.Contains(section, "Weekly"):
+		return &recurrence{Freq: weekly, Interval: 1, ByDay: []nthWeekday{{Weekday: time.Monday}}}
+	}
+	return nil
+}
+
+// parseRecurrence finds a rule in a task's text, written as `every: 2nd tuesday`, `rrule:FREQ=MONTHLY;BYDAY=2TU`,
+// or `after-done: 3d` (for 3 days after it was last done).
+// The text is returned without the rule. A nil rule means there wasn't one
+func parseRecurrence(s string) (*recurrence, string, error) {
+	return findRecurrence(s, false)
+}
+
+// parseRecurringText is parseRecurrence for the items in recurring.md, where `every` may also be written without its colon
+func parseRecurringText(s string) (*recurrence, string, error) {
+	return findRecurrence(s, true)
+}
+
+func findRecurrence(s string, bare bool) (*recurrence, string, error) {
+	if m := afterDone.FindStringSubmatchIndex(s); m != nil {
+		n, _ := strconv.Atoi(s[m[2]:m[3]])
+		r := &recurrence{Interval: n, AfterDone: true}
+		r.Freq = map[string]recurType{"d": daily, "w": weekly, "m": monthly}[strings.ToLower(s[m[4]:m[5]])]
+		return r, s[:m[0]] + s[m[1]:], r.validate()
+	}
+	if m := rruleRegexp.FindStringSubmatchIndex(s); m != nil {
+		r, err := parseRRule(s[m[2]:m[3]])
+		return r, s[:m[0]] + s[m[1]:], err
+	}
+	m := everyRegexp.FindStringIndex(s)
+	if b := bareEvery.FindStringSubmatchIndex(s); bare && m == nil && b != nil {
+		m = []int{b[0], b[2]}
+	}
+	if m == nil {
+		return nil, s, nil
+	}
+	var (
+		words = []string{}
+		end   = m[1]
+		rest  = s[m[1]:]
+		spans = regexp.MustCompile(`\S+`).FindAllStringIndex(rest, -1)
+	)
+	word := func(i int) string {
+		if i >= len(spans) {
+			return ""
+		}
+		return strings.ToLower(strings.TrimRight(rest[spans[i][0]:spans[i][1]], ","))
+	}
+	for i, span := range spans {
+		w := word(i)
+		switch w {
+		case "at", "from", "to":
+			// only when followed by a time of day
+			if !clockRegexp.MatchString(word(i + 1)) {
+				w = ""
+			}
+		case "and":
+			// only when followed by another time or day, e.g. `at 09:30 and 17:00`, rather than `and summarise it`
+			next := word(i + 1)
+			if _, ok := weekdayNames[next]; !ok && next != "at" && !clockRegexp.MatchString(next) && !ordinal.MatchString(next) &&
+				!monthDay.MatchString(next) {
+				w = ""
+			}
+		default:
+			if !isRecurrenceWord(w) {
+				w = ""
+			}
+		}
+		if w == "" {
+			break
+		}
+		words = append(words, w)
+		end = m[1] + span[1]
+	}
+	r, err := parseEvery(words)
+	return r, strings.TrimRight(s[:m[0]], " ") + s[end:], err
+}
+
+func isRecurrenceWord(w string) bool {
+	switch w {
+	case "", "on", "the", "of", "and", "every", "other", "last":
+		return w != ""
+	}
+	if _, ok := weekdayNames[w]; ok {
+		return true
+	}
+	if _, ok := unitFreq(w); ok {
+		return true
+	}
+	if _, err := strconv.Atoi(w); err == nil {
+		return true
+	}
+	return compactUnit.MatchString(w) || ordinal.MatchString(w) || monthDay.MatchString(w) || clockRegexp.MatchString(w)
+}
+
+func unitFreq(w string) (recurType, bool) {
+	switch w {
+	case "hour", "hours", "hourly":
+		return hourly, true
+	case "day", "days", "daily":
+		return daily, true
+	case "week", "weeks", "weekly":
+		return weekly, true
+	case "weekday", "weekdays":
+		return weekdays, true
+	case "month", "months", "monthly":
+		return monthly, true
+	case "year", "years", "yearly", "annually":
+		return yearly, true
+	}
+	return "", false
+}
+
+func parseEvery(words []string) (*recurrence, error) {
+	r := &recurrence{Interval: 1}
+	var (
+		pendingN   = 0 // an ordinal waiting for its weekday
+		start, end = -1, -1
+	)
+	for i, w := range words {
+		if m := compactUnit.FindStringSubmatch(w); m != nil {
+			r.Interval, _ = strconv.Atoi(m[1])
+			r.Freq = map[string]recurType{"h": hourly, "d": daily, "w": weekly, "m": monthly, "y": yearly}[m[2]]
+			continue
+		}
+		if mins, ok := parseClock(w); ok {
+			switch {
+			case i > 0 && words[i-1] == "from":
+				start = mins
+			case i > 0 && words[i-1] == "to":
+				end = mins
+			default:
+				r.Times = append(r.Times, mins)
+			}
+			continue
+		}
+		if f, ok := unitFreq(w); ok {
+			if pendingN != 0 && f == daily {
+				// the last day of the month
+				r.ByMonthDay = append(r.ByMonthDay, pendingN)
+				pendingN = 0
+				continue
+			}
+			if r.Freq != "" && r.Freq != f {
+				return nil, fmt.Errorf("'%s' conflicts with %s", w, r.Freq)
+			}
+			r.Freq = f
+			continue
+		}
+		if wd, ok := weekdayNames[w]; ok {
+			r.ByDay = append(r.ByDay, nthWeekday{N: pendingN, Weekday: wd})
+			if r.Freq == "" {
+				if pendingN != 0 {
+					r.Freq = monthly
+				} else {
+					r.Freq = weekly
+				}
+			}
+			pendingN = 0
+			continue
+		}
+		if m := ordinal.FindStringSubmatch(w); m != nil || w == "last" {
+			pendingN = -1
+			if m != nil {
+				pendingN, _ = strconv.Atoi(m[1])
+			}
+			continue
+		}
+		if m := monthDay.FindStringSubmatch(w); m != nil {
+			month, _ := strconv.Atoi(m[1])
+			d, _ := strconv.Atoi(m[2])
+			r.ByMonth = append(r.ByMonth, time.Month(month))
+			r.ByMonthDay = append(r.ByMonthDay, d)
+			if r.Freq == "" {
+				r.Freq = yearly
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(w); err == nil {
+			if r.Freq == "" && i+1 < len(words) {
+				r.Interval = n
+			} else {
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+			continue
+		}
+		if w == "other" {
+			r.Interval = 2
+		}
+	}
+	if pendingN != 0 {
+		// e.g. '1st of the month'
+		r.ByMonthDay = append(r.ByMonthDay, pendingN)
+	}
+	if r.Freq == "" && len(r.ByMonthDay) > 0 {
+		r.Freq = monthly
+	}
+	if r.Freq == weekdays {
+		r.Freq = weekly
+		r.ByDay = mondayToFriday
+	}
+	if r.Freq == hourly {
+		if start < 0 {
+			start = 0
+		}
+		if end < 0 {
+			end = 24*60 - 1
+		}
+		r.Times = []int{}
+		for t := start; t <= end; t += 60 * r.Interval {
+			r.Times = append(r.Times, t)
+		}
+	} else if start >= 0 || end >= 0 {
+		return nil, errors.New("'from' and 'to' times are for hourly tasks")
+	}
+	sort.Ints(r.Times)
+	return r, r.validate()
+}
+
+// parseRRule parses the value of an RRULE, e.g. FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH
+func parseRRule(s string) (*recurrence, error) {
+	var (
+		r       = &recurrence{Interval: 1}
+		hours   = []int{}
+		minutes = []int{}
+	)
+	for _, part := range strings.Split(strings.ToUpper(s), ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("rrule part '%s' should be KEY=VALUE", part)
+		}
+		switch kv[0] {
+		case "FREQ":
+			r.Freq = recurType(strings.ToLower(kv[1]))
+		case "INTERVAL":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("rrule INTERVAL '%s' is not a number", kv[1])
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, d := range strings.Split(kv[1], ",") {
+				if len(d) < 2 {
+					return nil, fmt.Errorf("rrule BYDAY '%s' is not valid", d)
+				}
+				wd, ok := rruleDays[d[len(d)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("rrule BYDAY '%s' is not valid", d)
+				}
+				n := 0
+				if len(d) > 2 {
+					var err error
+					if n, err = strconv.Atoi(d[:len(d)-2]); err != nil {
+						return nil, fmt.Errorf("rrule BYDAY '%s' is not valid", d)
+					}
+				}
+				r.ByDay = append(r.ByDay, nthWeekday{N: n, Weekday: wd})
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(kv[1], ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil {
+					return nil, fmt.Errorf("rrule BYMONTHDAY '%s' is not a number", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, m := range strings.Split(kv[1], ",") {
+				n, err := strconv.Atoi(m)
+				if err != nil {
+					return nil, fmt.Errorf("rrule BYMONTH '%s' is not a number", m)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "BYHOUR", "BYMINUTE":
+			for _, v := range strings.Split(kv[1], ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					return nil, fmt.Errorf("rrule %s '%s' is not a number", kv[0], v)
+				}
+				if kv[0] == "BYHOUR" {
+					hours = append(hours, n)
+				} else {
+					minutes = append(minutes, n)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("rrule %s is not supported", kv[0])
+		}
+	}
+	if r.Freq == hourly && len(hours) == 0 {
+		for h := 0; h < 24; h += r.Interval {
+			hours = append(hours, h)
+		}
+	}
+	if len(hours) > 0 && len(minutes) == 0 {
+		minutes = []int{0}
+	}
+	for _, h := range hours {
+		for _, m := range minutes {
+			if h < 0 || h > 23 || m < 0 || m > 59 {
+				return nil, fmt.Errorf("rrule time %d:%02d is not valid", h, m)
+			}
+			r.Times = append(r.Times, h*60+m)
+		}
+	}
+	sort.Ints(r.Times)
+	return r, r.validate()
+}
+
+// parseClock reads a time of day such as 09:30, as minutes after midnight
+func parseClock(s string) (int, bool) {
+	m := clockRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	return h*60 + min, true
+}
+
+func formatClock(mins int) string {
+	return fmt.Sprintf("%02d:%02d", mins/60, mins%60)
+}
+
+func (r *recurrence) validate() error {
+	switch r.Freq {
+	case hourly, daily, weekly, monthly, yearly:
+	case "":
+		return errors.New("recurrence has no frequency (e.g. daily, weekly, monthly)")
+	default:
+		return fmt.Errorf("recurrence frequency '%s' is not supported", r.Freq)
+	}
+	if r.Interval < 1 {
+		return fmt.Errorf("recurrence interval %d should be at least 1", r.Interval)
+	}
+	for _, d := range r.ByMonthDay {
+		if d == 0 || d > 31 || d < -31 {
+			return fmt.Errorf("day of month %d is not valid", d)
+		}
+	}
+	for _, m := range r.ByMonth {
+		if m < time.January || m > time.December {
+			return fmt.Errorf("month %d is not valid", m)
+		}
+	}
+	return nil
+}
+
+// String formats the rule as an RRULE value
+func (r *recurrence) String() string {
+	parts := []string{"FREQ=" + strings.ToUpper(string(r.Freq))}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := []string{}
+		for _, d := range r.ByDay {
+			s := strings.ToUpper(d.Weekday.String()[:2])
+			if d.N != 0 {
+				s = strconv.Itoa(d.N) + s
+			}
+			days = append(days, s)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := []string{}
+		for _, d := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(d))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		months := []string{}
+		for _, m := range r.ByMonth {
+			months = append(months, strconv.Itoa(int(m)))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if r.timesInRule() {
+		var hours, minutes []string
+		seen := map[string]bool{}
+		for _, t := range r.Times {
+			h, m := "H"+strconv.Itoa(t/60), "M"+strconv.Itoa(t%60)
+			if !seen[h] {
+				hours = append(hours, h[1:])
+			}
+			if !seen[m] {
+				minutes = append(minutes, m[1:])
+			}
+			seen[h], seen[m] = true, true
+		}
+		parts = append(parts, "BYHOUR="+strings.Join(hours, ","), "BYMINUTE="+strings.Join(minutes, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// timesInRule reports whether the times are every combination of their hours and minutes, which is all that an RRULE's
+// BYHOUR and BYMINUTE can say. Otherwise String leaves them out, e.g. for 09:30 and 17:00
+func (r *recurrence) timesInRule() bool {
+	hours, minutes, times := map[int]bool{}, map[int]bool{}, map[int]bool{}
+	for _, t := range r.Times {
+		hours[t/60], minutes[t%60], times[t] = true, true, true
+	}
+	return len(times) > 0 && len(hours)*len(minutes) == len(times)
+}
+
+// occursOn reports whether the rule includes day d. Intervals count from `from`, when set.
+// Days outside of from and until never match
+func (r *recurrence) occursOn(d, from, until time.Time) bool {
+	d = day(d)
+	if (!from.IsZero() && d.Before(day(from))) || (!until.IsZero() && d.After(day(until))) {
+		return false
+	}
+	anchor := recurrenceAnchor
+	if !from.IsZero() {
+		anchor = day(from)
+	}
+	var periods int
+	switch r.Freq {
+	case hourly:
+		// the interval is within the day
+	case daily:
+		periods = int(d.Sub(anchor).Hours()+12) / 24
+	case weekly:
+		periods = int(startOfWeek(d).Sub(startOfWeek(anchor)).Hours()+12) / 24 / 7
+	case monthly:
+		periods = (d.Year()-anchor.Year())*12 + int(d.Month()) - int(anchor.Month())
+	case yearly:
+		periods = d.Year() - anchor.Year()
+	}
+	if periods < 0 {
+		periods = -periods
+	}
+	if periods%r.Interval != 0 {
+		return false
+	}
+	if len(r.ByMonth) > 0 && !containsMonth(r.ByMonth, d.Month()) {
+		return false
+	}
+	switch r.Freq {
+	case hourly, daily:
+		return len(r.ByDay) == 0 || r.matchesWeekday(d)
+	case weekly:
+		if len(r.ByDay) == 0 {
+			return d.Weekday() == anchor.Weekday()
+		}
+		return r.matchesWeekday(d)
+	}
+	// monthly or yearly
+	if r.Freq == yearly && len(r.ByMonth) == 0 && d.Month() != anchor.Month() {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.Local).Day()
+		for _, md := range r.ByMonthDay {
+			if md == d.Day() || (md < 0 && last+md+1 == d.Day()) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(r.ByDay) > 0 {
+		return r.matchesWeekday(d)
+	}
+	return d.Day() == anchor.Day()
+}
+
+// matchesWeekday checks ByDay, where an N counts within the month
+func (r *recurrence) matchesWeekday(d time.Time) bool {
+	last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.Local).Day()
+	for _, bd := range r.ByDay {
+		if bd.Weekday != d.Weekday() {
+			continue
+		}
+		switch {
+		case bd.N == 0:
+			return true
+		case bd.N > 0 && (d.Day()-1)/7+1 == bd.N:
+			return true
+		case bd.N < 0 && (last-d.Day())/7+1 == -bd.N:
+			return true
+		}
+	}
+	return false
+}
+
+// next returns up to n days on which the rule occurs, starting at d
+func (r *recurrence) next(d, from, until time.Time, n int) []time.Time {
+	ret := []time.Time{}
+	end := day(d).AddDate(5, 0, 0)
+	for d = day(d); len(ret) < n && d.Before(end); d = d.AddDate(0, 0, 1) {
+		if !until.IsZero() && d.After(day(until)) {
+			break
+		}
+		if r.occursOn(d, from, until) {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+func startOfWeek(d time.Time) time.Time {
+	return day(d).AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+func containsMonth(months []time.Month, m time.Month) bool {
+	for _, v := range months {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// recurringFor takes the items from recurring.md which occur on day d, with their rules removed.
+// Items with times of day are repeated for each time, and come after the others in chronological order.
+// after-done items occur once their interval has passed since the completion recorded in done
+func recurringFor(recurring tasks, d time.Time, done completions) []*blackfriday.Node {
+	type timedItem struct {
+		node *blackfriday.Node
+		mins int
+	}
+	var (
+		untimed = []*blackfriday.Node{}
+		timed   = []timedItem{}
+		section = ""
+	)
+	for n := recurring.node.FirstChild; n != nil; n = n.Next {
+		switch n.Type {
+		case blackfriday.Heading:
+			section = inlineText(n)
+		case blackfriday.List:
+			for item := n.FirstChild; item != nil; {
+				next := item.Next
+				r, from, until := itemRecurrence(item, section)
+				if r != nil && r.AfterDone {
+					if next := r.nextAfterDone(done[recurringKey(inlineText(item.FirstChild))]); next.After(day(d)) {
+						r = nil
+					}
+				} else if r != nil && !r.occursOn(d, from, until) {
+					r = nil
+				}
+				if r != nil {
+					item.Unlink()
+					times := itemTimes(r, inlineText(item.FirstChild))
+					stripRecurrence(item)
+					if len(times) == 0 {
+						untimed = append(untimed, item)
+					}
+					for _, mins := range times {
+						c := cloneNode(item)
+						appendText(c, " at:"+formatClock(mins))
+						timed = append(timed, timedItem{node: c, mins: mins})
+					}
+				}
+				item = next
+			}
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].mins < timed[j].mins })
+	for _, t := range timed {
+		untimed = append(untimed, t.node)
+	}
+	return untimed
+}
+
+// itemTimes are the times of day from the rule and from any `at:09:30` in the text
+func itemTimes(r *recurrence, text string) []int {
+	times := append([]int{}, r.Times...)
+	for _, m := range atRegexp.FindAllStringSubmatch(text, -1) {
+		if mins, ok := parseClock(m[1]); ok {
+			times = append(times, mins)
+		}
+	}
+	sort.Ints(times)
+	ret := []int{}
+	for i, t := range times {
+		if i == 0 || t != times[i-1] {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// itemRecurrence is the rule for an item in recurring.md, falling back to the rule for its section
+func itemRecurrence(item *blackfriday.Node, section string) (*recurrence, time.Time, time.Time) {
+	var from, until time.Time
+	if item.FirstChild == nil || item.FirstChild.Type != blackfriday.Paragraph {
+		return nil, from, until
+	}
+	text := inlineText(item.FirstChild)
+	r, _, err := parseRecurringText(text)
+	if err != nil {
+		return nil, from, until
+	}
+	if r == nil {
+		r = sectionRecurrence(section)
+	}
+	for _, m := range boundsRegexp.FindAllStringSubmatch(text, -1) {
+		b, err := parseDate(m[2], time.Now())
+		if err != nil {
+			continue
+		}
+		if m[1] == "from" {
+			from = b
+		} else {
+			until = b
+		}
+	}
+	return r, from, until
+}
+
+// stripRecurrence removes the rule, its bounds and times from an item's text
+func stripRecurrence(item *blackfriday.Node) {
+	for n := item.FirstChild.FirstChild; n != nil; n = n.Next {
+		if n.Type == blackfriday.Text {
+			n.Literal = []byte(stripRecurringText(string(n.Literal)))
+		}
+	}
+}
+
+// stripRecurrenceText removes a rule, its bounds and times from a task's text
+func stripRecurrenceText(s string) string {
+	_, rest, _ := parseRecurrence(s)
+	return stripRuleTokens(rest)
+}
+
+// stripRecurringText is stripRecurrenceText for the items in recurring.md
+func stripRecurringText(s string) string {
+	_, rest, _ := parseRecurringText(s)
+	return stripRuleTokens(rest)
+}
+
+func stripRuleTokens(rest string) string {
+	rest = ifMissed.ReplaceAllString(rest, "")
+	return atRegexp.ReplaceAllString(boundsRegexp.ReplaceAllString(rest, ""), "")
+}
+
+// if-missed policies, for recurring tasks which weren't done on the day
+const (
+	missedSkip    = "skip"    // the default: they are dropped at rollover
+	missedCarry   = "carry"   // rolled over once
+	missedOverdue = "overdue" // rolled into Overdue, until done
+)
+
+// missedPolicies maps recurring tasks (by recurringKey) to their `if-missed:` policy
+func missedPolicies(recurring tasks) map[string]string {
+	policies := map[string]string{}
+	recurring.node.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && node.Type == blackfriday.Item && node.FirstChild != nil && node.FirstChild.Type == blackfriday.Paragraph {
+			text := inlineText(node.FirstChild)
+			policy := missedSkip
+			if m := ifMissed.FindStringSubmatch(text); m != nil {
+				policy = m[1]
+			}
+			policies[recurringKey(text)] = policy
+		}
+		return blackfriday.GoToNext
+	})
+	return policies
+}
+
+// carryMissed applies if-missed policies to undone items from the old Daily section. Overdue ones get a due date
+// (the day they were missed), so they are routed into Overdue
+func carryMissed(nodes []*blackfriday.Node, policies map[string]string, ref time.Time) []*blackfriday.Node {
+	return filterItems(nodes, func(item *blackfriday.Node) bool {
+		t, ok := parseTask(item, ref)
+		if !ok || t.IsClosed() {
+			return false
+		}
+		switch policies[recurringKey(t.Description)] {
+		case missedCarry:
+			return true
+		case missedOverdue:
+			if t.Due.IsZero() {
+				appendText(item, " due:"+ref.Format("2006-01-02"))
+			}
+			return true
+		}
+		return false
+	})
+}
+
+// dropCarried removes recurring items with the 'carry' policy, as they have already been carried once
+func dropCarried(nodes []*blackfriday.Node, policies map[string]string) []*blackfriday.Node {
+	return filterItems(nodes, func(item *blackfriday.Node) bool {
+		if item.FirstChild == nil || item.FirstChild.Type != blackfriday.Paragraph {
+			return true
+		}
+		return policies[recurringKey(inlineText(item.FirstChild))] != missedCarry
+	})
+}
+
+// dedupRecurring skips recurring items which are already present and open (e.g. carried over), or repeated.
+// present is from openKeys, and is updated
+func dedupRecurring(present map[string]bool, items []*blackfriday.Node) []*blackfriday.Node {
+	ret := []*blackfriday.Node{}
+	for _, item := range items {
+		if item.FirstChild == nil || item.FirstChild.Type != blackfriday.Paragraph {
+			ret = append(ret, item)
+			continue
+		}
+		k := dedupKey(inlineText(item.FirstChild))
+		if !present[k] {
+			present[k] = true
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// openKeys are the dedupKeys of the open tasks in the given nodes
+func openKeys(nodes []*blackfriday.Node) map[string]bool {
+	present := map[string]bool{}
+	for _, n := range nodes {
+		if n.Type != blackfriday.List {
+			continue
+		}
+		for _, t := range flatten(tasks{node: n}.Tasks()) {
+			if !t.IsClosed() {
+				present[dedupKey(t.Description)] = true
+			}
+		}
+	}
+	return present
+}
+
+// dedupKey is the recurringKey, but distinguishing between the times of the same task
+func dedupKey(text string) string {
+	key := recurringKey(text)
+	if m := atRegexp.FindStringSubmatch(text); m != nil {
+		key += " at:" + m[1]
+	}
+	return key
+}
+
+// completions records the last day each task was done, keyed by recurringKey
+type completions map[string]time.Time
+
+// recurringKey identifies a recurring task, both in recurring.md and in the copies rolled into today.md
+func recurringKey(description string) string {
+	if _, ok := parseStatus(description); ok {
+		description = description[3:]
+	}
+	// carried items may have been given a due date
+	description = dateTokenRegexp.ReplaceAllString(stripRecurringText(description), "")
+	return strings.ToLower(strings.Join(strings.Fields(description), " "))
+}
+
+// loadCompletions scans the archives, and finally the given tasks (dated by their heading), for tasks which were done.
+// Only the archives within reach of the after-done rules in recurring, as of day d, are read
+func loadCompletions(recurring tasks, d time.Time, extra ...tasks) (completions, error) {
+	c := completions{}
+	if since, ok := completionsSince(recurring, d); ok {
+		dates, err := getArchiveDates()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range dates {
+			if a.Before(since) {
+				continue
+			}
+			t, err := loadArchive(a)
+			if err != nil {
+				return nil, err
+			}
+			c.record(t, a)
+		}
+	}
+	for _, t := range extra {
+		if hd, ok := t.headingDate(); ok {
+			c.record(t, hd)
+		}
+	}
+	return c, nil
+}
+
+// record notes the tasks which were done in t, on day d
+func (c completions) record(t tasks, d time.Time) {
+	for _, tk := range flatten(t.Tasks()) {
+		if tk.Status == "x" && d.After(c[recurringKey(tk.Description)]) {
+			c[recurringKey(tk.Description)] = d
+		}
+	}
+}
+
+// lastDone reads back through the archives, from the most recent, for the last day a task was done.
+// It is the zero time if it never was
+func lastDone(key string) (time.Time, error) {
+	dates, err := getArchiveDates()
+	if err != nil {
+		return time.Time{}, err
+	}
+	for i := len(dates) - 1; i >= 0; i-- {
+		t, err := loadArchive(dates[i])
+		if err != nil {
+			return time.Time{}, err
+		}
+		c := completions{}
+		c.record(t, dates[i])
+		if d, ok := c[key]; ok {
+			return d, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+func loadArchive(d time.Time) (tasks, error) {
+	fa, err := getArchiveFilename(d)
+	if err != nil {
+		return tasks{}, err
+	}
+	return parseFile(fa)
+}
+
+// completionsSince is the first day whose completions matter on day d: the earliest completion which would still
+// hold back an after-done task in recurring. Tasks done before then are due again either way.
+// ok is false when there are no after-done rules
+func completionsSince(recurring tasks, d time.Time) (since time.Time, ok bool) {
+	d = day(d)
+	since = d
+	section := ""
+	for n := recurring.node.FirstChild; n != nil; n = n.Next {
+		switch n.Type {
+		case blackfriday.Heading:
+			section = inlineText(n)
+		case blackfriday.List:
+			for item := n.FirstChild; item != nil; item = item.Next {
+				r, _, _ := itemRecurrence(item, section)
+				if r == nil || !r.AfterDone {
+					continue
+				}
+				// step back, over the days which month ends make irregular (Jan 31 + 1 month is Mar 3)
+				for k := 1; k <= 3; k++ {
+					if r.nextAfterDone(since.AddDate(0, 0, -k)).After(d) {
+						since, k = since.AddDate(0, 0, -k), 0
+					}
+				}
+				ok = true
+			}
+		}
+	}
+	return since, ok
+}
+
+// afterDoneInterval is the interval of an after-done rule, as written, e.g. `3d`
+func (r *recurrence) afterDoneInterval() string {
+	return strconv.Itoa(r.Interval) + map[recurType]string{daily: "d", weekly: "w", monthly: "m"}[r.Freq]
+}
+
+// nextAfterDone is the day an after-done task is due again, or the zero time if it was never done
+func (r *recurrence) nextAfterDone(last time.Time) time.Time {
+	if last.IsZero() {
+		return last
+	}
+	switch r.Freq {
+	case weekly:
+		return day(last).AddDate(0, 0, 7*r.Interval)
+	case monthly:
+		return day(last).AddDate(0, r.Interval, 0)
+	}
+	return day(last).AddDate(0, 0, r.Interval)
+}
+
+func recurringCmd(args []string) error {
+	if len(args) < 2 || args[1] != "next" {
+		return errors.New("usage: today recurring next [-n count]")
+	}
+	fs := flag.NewFlagSet("recurring next", flag.ContinueOnError)
+	count := fs.Int("n", 3, "number of occurrences to show per task")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	recurring, err := loadRecurring()
+	if err != nil {
+		return err
+	}
+	today, err := loadToday()
+	if err != nil {
+		return err
+	}
+	done, err := loadCompletions(recurring, time.Now(), today)
+	if err != nil {
+		return err
+	}
+	section := ""
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for n := recurring.node.FirstChild; n != nil; n = n.Next {
+		switch n.Type {
+		case blackfriday.Heading:
+			section = inlineText(n)
+		case blackfriday.List:
+			for item := n.FirstChild; item != nil; item = item.Next {
+				if item.FirstChild == nil || item.FirstChild.Type != blackfriday.Paragraph {
+					continue
+				}
+				text := inlineText(item.FirstChild)
+				r, from, until := itemRecurrence(item, section)
+				if _, _, err := parseRecurringText(text); err != nil {
+					fmt.Fprintf(w, "%s\t%v\n", text, err)
+					continue
+				}
+				if r == nil {
+					continue
+				}
+				dates := []string{}
+				if r.AfterDone {
+					last, ok := done[recurringKey(text)]
+					if !ok {
+						// done before the archives which rollover reads, if at all
+						if last, err = lastDone(recurringKey(text)); err != nil {
+							return err
+						}
+					}
+					next := r.nextAfterDone(last)
+					if next.Before(day(time.Now())) {
+						next = day(time.Now())
+					}
+					dates = append(dates, next.Format("2006-01-02 Mon"))
+					if !last.IsZero() {
+						dates = append(dates, "(last done "+last.Format("2006-01-02")+")")
+					}
+				}
+				for _, d := range r.next(time.Now(), from, until, *count) {
+					if r.AfterDone {
+						break
+					}
+					dates = append(dates, d.Format("2006-01-02 Mon"))
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", text, r, strings.Join(dates, ", "))
+			}
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEvery(t *testing.T) {
+	for _, tc := range []struct {
+		every string
+		rule  string // as an RRULE, or "" for an error
+	}{
+		{"day", "FREQ=DAILY"},
+		{"2 weeks", "FREQ=WEEKLY;INTERVAL=2"},
+		{"other week", "FREQ=WEEKLY;INTERVAL=2"},
+		{"3d", "FREQ=DAILY;INTERVAL=3"},
+		{"weekdays", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{"monday 09:30", "FREQ=WEEKLY;BYDAY=MO;BYHOUR=9;BYMINUTE=30"},
+		{"2nd tuesday", "FREQ=MONTHLY;BYDAY=2TU"},
+		{"last friday", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"last day of the month", "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"31st", "FREQ=MONTHLY;BYMONTHDAY=31"},
+		{"02-29", "FREQ=YEARLY;BYMONTHDAY=29;BYMONTH=2"},
+		{"2h from 09:00 to 17:00", "FREQ=HOURLY;INTERVAL=2;BYHOUR=9,11,13,15,17;BYMINUTE=0"},
+		{"daily weekly", ""},
+		{"day from 09:00", ""},
+		{"32nd", ""},
+		{"", ""},
+	} {
+		t.Run(tc.every, func(t *testing.T) {
+			r, err := parseEvery(strings.Fields(tc.every))
+			switch {
+			case tc.rule == "" && err == nil:
+				t.Errorf("expected an error, got %s", r)
+			case tc.rule != "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tc.rule != "" && r.String() != tc.rule:
+				t.Errorf("rule is %s, expected %s", r, tc.rule)
+			}
+		})
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	for _, tc := range []struct {
+		rrule string
+		rule  string // as formatted, or "" for an error
+	}{
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH"},
+		{"freq=monthly;byday=-1fr", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "FREQ=YEARLY;BYMONTHDAY=29;BYMONTH=2"},
+		{"FREQ=HOURLY;INTERVAL=6", "FREQ=HOURLY;INTERVAL=6;BYHOUR=0,6,12,18;BYMINUTE=0"},
+		{"FREQ=DAILY;BYHOUR=9;BYMINUTE=15,45", "FREQ=DAILY;BYHOUR=9;BYMINUTE=15,45"},
+		{"FREQ=WEEKLY;BYDAY=XX", ""},
+		{"FREQ=DAILY;COUNT=3", ""},
+		{"FREQ=DAILY;INTERVAL=0", ""},
+		{"FREQ=DAILY;BYHOUR=24", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", ""},
+		{"FREQ=SECONDLY", ""},
+		{"INTERVAL=2", ""},
+		{"FREQ", ""},
+	} {
+		t.Run(tc.rrule, func(t *testing.T) {
+			r, err := parseRRule(tc.rrule)
+			switch {
+			case tc.rule == "" && err == nil:
+				t.Errorf("expected an error, got %s", r)
+			case tc.rule != "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tc.rule != "" && r.String() != tc.rule:
+				t.Errorf("rule is %s, expected %s", r, tc.rule)
+			}
+		})
+	}
+}
+
+func TestOccursOn(t *testing.T) {
+	date := func(s string) time.Time {
+		if s == "" {
+			return time.Time{}
+		}
+		d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	for _, tc := range []struct {
+		rrule       string
+		from, until string
+		day         string
+		expected    bool
+	}{
+		// the last day of the month
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "", "", "2026-02-28", true},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "", "", "2028-02-28", false},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "", "", "2028-02-29", true},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "", "", "2026-04-30", true},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "", "", "2026-03-30", false},
+		// months without the day are skipped
+		{"FREQ=MONTHLY;BYMONTHDAY=31", "", "", "2026-04-30", false},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", "", "", "2026-05-31", true},
+		{"FREQ=MONTHLY", "2026-01-31", "", "2026-02-28", false},
+		{"FREQ=MONTHLY", "2026-01-31", "", "2026-03-31", true},
+		// leap days
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "", "", "2028-02-29", true},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "", "", "2026-02-28", false},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "", "", "2026-03-01", false},
+		{"FREQ=YEARLY", "2024-02-29", "", "2025-02-28", false},
+		{"FREQ=YEARLY", "2024-02-29", "", "2028-02-29", true},
+		{"FREQ=DAILY;INTERVAL=3", "2028-02-27", "", "2028-03-01", true},
+		{"FREQ=DAILY;INTERVAL=3", "2028-02-27", "", "2028-02-29", false},
+		// nth weekdays
+		{"FREQ=MONTHLY;BYDAY=-1FR", "", "", "2026-10-30", true},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "", "", "2026-10-23", false},
+		{"FREQ=MONTHLY;BYDAY=2TU", "", "", "2026-10-13", true},
+		{"FREQ=MONTHLY;BYDAY=2TU", "", "", "2026-10-06", false},
+		// intervals count from the from date, and across the year
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "2026-10-05", "", "2026-10-19", true},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "2026-10-05", "", "2026-10-12", false},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "2026-12-28", "", "2027-01-11", true},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "2026-12-28", "", "2027-01-04", false},
+		{"FREQ=WEEKLY", "2026-10-07", "", "2026-10-14", true},
+		{"FREQ=WEEKLY", "2026-10-07", "", "2026-10-15", false},
+		// bounds
+		{"FREQ=DAILY", "2026-10-05", "", "2026-10-04", false},
+		{"FREQ=DAILY", "", "2026-10-20", "2026-10-20", true},
+		{"FREQ=DAILY", "", "2026-10-20", "2026-10-21", false},
+	} {
+		t.Run(tc.rrule+" "+tc.day, func(t *testing.T) {
+			r, err := parseRRule(tc.rrule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.occursOn(date(tc.day), date(tc.from), date(tc.until)); got != tc.expected {
+				t.Errorf("occursOn(%s) from '%s' until '%s' is %v, expected %v", tc.day, tc.from, tc.until, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRecurrenceTimesRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		text  string
+		times []int
+		rule  string
+	}{
+		{"every: daily at 09:30 and 17:00", []int{570, 1020}, "FREQ=DAILY"},
+		{"every: daily at 09:00 and 09:30 and 10:00 and 10:30", []int{540, 570, 600, 630}, "FREQ=DAILY;BYHOUR=9,10;BYMINUTE=0,30"},
+		{"every 2h from 09:00 to 13:00", []int{540, 660, 780}, "FREQ=HOURLY;INTERVAL=2;BYHOUR=9,11,13;BYMINUTE=0"},
+		{"every: monday at 08:15", []int{495}, "FREQ=WEEKLY;BYDAY=MO;BYHOUR=8;BYMINUTE=15"},
+	} {
+		t.Run(tc.text, func(t *testing.T) {
+			r, _, err := parseRecurringText(tc.text)
+			if err != nil || r == nil {
+				t.Fatalf("%s: %v", tc.text, err)
+			}
+			if r.String() != tc.rule {
+				t.Errorf("rule is %s, expected %s", r, tc.rule)
+			}
+			// as imported into recurring.md
+			line := taskLine(task{Status: " ", Description: "Stand up", Recurrence: r})
+			back, _, err := parseRecurringText(line)
+			if err != nil || back == nil {
+				t.Fatalf("%s: %v", line, err)
+			}
+			if got := itemTimes(back, line); !equalInts(got, tc.times) {
+				t.Errorf("'%s' has times %v, expected %v", line, got, tc.times)
+			}
+		})
+	}
+}
+
+func TestBareEvery(t *testing.T) {
+	for _, tc := range []struct {
+		text      string
+		recurring bool // in recurring.md
+		rule      string
+		rest      string
+	}{
+		{"Stretch every 2h from 09:00 to 17:00", true, "FREQ=HOURLY;INTERVAL=2;BYHOUR=9,11,13,15,17;BYMINUTE=0", "Stretch"},
+		{"Stretch every 2h from 09:00 to 17:00", false, "", "Stretch every 2h from 09:00 to 17:00"},
+		{"Ask why backups run every 2 days", false, "", "Ask why backups run every 2 days"},
+		{"Read the report every 2 days and summarise it", true, "FREQ=DAILY;INTERVAL=2", "Read the report and summarise it"},
+		{"Read the report every: 2 days and summarise it", false, "FREQ=DAILY;INTERVAL=2", "Read the report and summarise it"},
+		{"Gym every: monday and thursday", false, "FREQ=WEEKLY;BYDAY=MO,TH", "Gym"},
+	} {
+		t.Run(tc.text, func(t *testing.T) {
+			find := parseRecurrence
+			if tc.recurring {
+				find = parseRecurringText
+			}
+			r, rest, err := find(tc.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tc.rule == "" && r != nil:
+				t.Errorf("expected no rule, got %s", r)
+			case tc.rule != "" && (r == nil || r.String() != tc.rule):
+				t.Errorf("rule is %v, expected %s", r, tc.rule)
+			}
+			if rest != tc.rest {
+				t.Errorf("text is '%s', expected '%s'", rest, tc.rest)
+			}
+		})
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCompletionsSince(t *testing.T) {
+	for _, tc := range []struct {
+		rule  string
+		day   string
+		since string
+	}{
+		{"after-done:3d", "2026-10-19", "2026-10-17"},
+		{"after-done:1d", "2026-10-19", "2026-10-19"},
+		{"after-done:2w", "2026-10-19", "2026-10-06"},
+		// Jan 31 + 1 month is Mar 3, which is after Mar 2, though Feb 1 + 1 month isn't
+		{"after-done:1m", "2026-03-02", "2026-01-31"},
+	} {
+		t.Run(tc.rule+" "+tc.day, func(t *testing.T) {
+			recurring, err := parse([]byte("# Recurring\n\n## Chores\n\n- [ ] Water the plants " + tc.rule + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, _ := time.ParseInLocation("2006-01-02", tc.day, time.Local)
+			since, ok := completionsSince(recurring, d)
+			if !ok || since.Format("2006-01-02") != tc.since {
+				t.Errorf("since is %s (%v), expected %s", since.Format("2006-01-02"), ok, tc.since)
+			}
+		})
+	}
+}
+
+func TestLoadCompletionsWindow(t *testing.T) {
+	home, err := ioutil.TempDir("", "today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+	for d, text := range map[string]string{
+		"2026/10/17": "# 2026-10-17, Saturday\n\n## Daily\n\n- [x] Water the plants\n",
+		"2026/10/16": "# 2026-10-16, Friday\n\n## Daily\n\n- [x] Feed the fish\n",
+	} {
+		file := filepath.Join(home, todayDir, filepath.FromSlash(d)+".today.md")
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recurring, err := parse([]byte("# Recurring\n\n## Chores\n\n- [ ] Water the plants after-done:3d\n- [ ] Feed the fish after-done:3d\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadCompletions(recurring, time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the window starts on the 17th, when the plants were watered
+	if got := c["water the plants"].Format("2006-01-02"); got != "2026-10-17" {
+		t.Errorf("the plants were last watered on %s, expected 2026-10-17", got)
+	}
+	if got, ok := c["feed the fish"]; ok {
+		t.Errorf("the fish were fed on %s, outside of the window", got.Format("2006-01-02"))
+	}
+	// as `recurring next` shows it
+	last, err := lastDone("feed the fish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := last.Format("2006-01-02"); got != "2026-10-16" {
+		t.Errorf("the fish were last fed on %s, expected 2026-10-16", got)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/laher/markdownfmt/markdown"
+	"github.com/russross/blackfriday/v2"
+)
+
+// archivedDay is the parsed archive for a single day
+type archivedDay struct {
+	date  time.Time
+	tasks []task
+}
+
+func review(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	week := fs.Bool("week", false, "review the week (Monday to Sunday) containing the given date")
+	month := fs.Bool("month", false, "review the month containing the given date")
+	dryRun := fs.Bool("dryrun", false, "print the review to stdout instead of writing it")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *week == *month {
+		return errors.New("specify one of --week or --month")
+	}
+	ref := time.Now()
+	if fs.NArg() > 0 {
+		var err error
+		ref, err = time.ParseInLocation("2006-01-02", fs.Arg(0), time.Local)
+		if err != nil {
+			return err
+		}
+	}
+	ref = time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, time.Local)
+
+	var (
+		from, until time.Time
+		title       string
+		file        string
+		err         error
+	)
+	if *week {
+		// ISO weeks start on a Monday
+		from = ref.AddDate(0, 0, -((int(ref.Weekday()) + 6) % 7))
+		until = from.AddDate(0, 0, 7)
+		year, w := from.ISOWeek()
+		title = fmt.Sprintf("Review %d-W%02d", year, w)
+		file, err = getWeekReviewFilename(from)
+	} else {
+		from = time.Date(ref.Year(), ref.Month(), 1, 0, 0, 0, 0, time.Local)
+		until = from.AddDate(0, 1, 0)
+		title = fmt.Sprintf("Review %s", from.Format("2006-01, January"))
+		file, err = getMonthReviewFilename(from)
+	}
+	if err != nil {
+		return err
+	}
+	archived, err := loadArchives(from, until)
+	if err != nil {
+		return err
+	}
+	if len(archived) == 0 {
+		return fmt.Errorf("no archives found from %s to %s", from.Format("2006-01-02"), until.AddDate(0, 0, -1).Format("2006-01-02"))
+	}
+	doc := buildReview(fmt.Sprintf("%s (%s to %s)", title, from.Format("2006-01-02"), until.AddDate(0, 0, -1).Format("2006-01-02")), archived)
+	if *dryRun {
+		r := markdown.NewRenderer(&markdown.Options{Terminal: false, HashHeaders: true})
+		render(r, os.Stdout, doc.node)
+		return nil
+	}
+	if err := newFile(file, doc); err != nil {
+		return err
+	}
+	fmt.Println(file)
+	return nil
+}
+
+// loadArchives parses each day's archive in [from, until), skipping days without one
+func loadArchives(from, until time.Time) ([]archivedDay, error) {
+	ret := []archivedDay{}
+	for d := from; d.Before(until); d = d.AddDate(0, 0, 1) {
+		fa, err := getArchiveFilename(d)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(fa); os.IsNotExist(err) {
+			continue
+		}
+		t, err := parseFile(fa)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, archivedDay{date: d, tasks: flatten(t.Tasks())})
+	}
+	return ret, nil
+}
+
+func buildReview(title string, archived []archivedDay) tasks {
+	doc := blackfriday.NewNode(blackfriday.Document)
+	headingNode(doc, 1, title)
+
+	headingNode(doc, 2, "Completed by day")
+	byTag := map[string][]string{}
+	for _, day := range archived {
+		var list *blackfriday.Node
+		for _, t := range day.tasks {
+			if t.Status != "x" {
+				continue
+			}
+			if list == nil {
+				headingNode(doc, 3, day.date.Format("2006-01-02, Monday"))
+				list = listNode(doc)
+			}
+			itemNode(list, "[x] "+t.Description)
+			tags := t.Tags
+			if len(tags) == 0 {
+				tags = []string{""}
+			}
+			for _, tag := range tags {
+				byTag[tag] = append(byTag[tag], fmt.Sprintf("[x] %s (%s)", t.Description, day.date.Format("Mon 02")))
+			}
+		}
+	}
+
+	headingNode(doc, 2, "Completed by tag")
+	tags := []string{}
+	for tag := range byTag {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		if tag == "" {
+			// untagged go last
+			continue
+		}
+		reviewList(doc, "#"+tag, byTag[tag])
+	}
+	if untagged, ok := byTag[""]; ok {
+		reviewList(doc, "Untagged", untagged)
+	}
+
+	// open items are whatever would be carried over from the most recent day
+	headingNode(doc, 2, "Still open")
+	last := archived[len(archived)-1]
+	var list *blackfriday.Node
+	for _, t := range last.tasks {
+		if t.IsClosed() || !(strings.Contains(t.Section, "Inbox") || strings.Contains(t.Section, "Rolled Over")) {
+			continue
+		}
+		if list == nil {
+			list = listNode(doc)
+		}
+		itemNode(list, fmt.Sprintf("[%s] %s", t.Status, t.Description))
+	}
+	return tasks{node: doc}
+}
+
+func reviewList(doc *blackfriday.Node, heading string, items []string) {
+	headingNode(doc, 3, heading)
+	list := listNode(doc)
+	for _, i := range items {
+		itemNode(list, i)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// apiTask is a task as served by the JSON API
+type apiTask struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	StatusName  string   `json:"statusName"`
+	Section     string   `json:"section"`
+	Tags        []string `json:"tags,omitempty"`
+	Contexts    []string `json:"contexts,omitempty"`
+	Projects    []string `json:"projects,omitempty"`
+	Priority    int      `json:"priority,omitempty"`
+	Due         string   `json:"due,omitempty"`
+	Scheduled   string   `json:"scheduled,omitempty"`
+	At          string   `json:"at,omitempty"`
+}
+
+// server serves today.md. Changes are serialised, and each request re-reads the file, as it may be edited elsewhere
+type server struct {
+	mu   sync.Mutex
+	addr string
+}
+
+func serve(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "the address to listen on")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	s := &server{addr: *addr}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tasks", s.handleTasks)
+	mux.HandleFunc("/tasks/", s.handleTask)
+	mux.HandleFunc("/rollover", s.handleRollover)
+	mux.HandleFunc("/archives", s.handleArchives)
+	mux.HandleFunc("/archives/", s.handleArchive)
+	mux.HandleFunc("/", s.handleToday)
+	verbosef("listening on http://%s", *addr)
+	return http.ListenAndServe(*addr, s.checkHost(mux))
+}
+
+// checkHost rejects requests for other hosts, so that a page which rebinds its own domain name to this address
+// can't read or change tasks
+func (s *server) checkHost(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r.Host, s.addr) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host '%s' is not served here", r.Host))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether a Host header names the address being served: its host, localhost, or an IP address,
+// on the same port
+func allowedHost(host, addr string) bool {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, "80"
+	}
+	addrName, addrPort, err := net.SplitHostPort(addr)
+	if err != nil || port != addrPort {
+		return false
+	}
+	name = strings.Trim(name, "[]")
+	return strings.EqualFold(name, addrName) || strings.EqualFold(name, "localhost") || net.ParseIP(name) != nil
+}
+
+// taskIDs identifies tasks by their section and description, so that an ID still works after other tasks change.
+// Repeated tasks are numbered
+func taskIDs(ts []task) []string {
+	seen := map[string]int{}
+	ids := []string{}
+	for _, t := range ts {
+		key := t.Section + "\x00" + t.Description
+		seen[key]++
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s\x00%d", key, seen[key])))
+		ids = append(ids, fmt.Sprintf("%x", sum[:4]))
+	}
+	return ids
+}
+
+func toAPITask(id string, t task) apiTask {
+	a := apiTask{
+		ID:          id,
+		Description: t.Description,
+		Status:      t.Status,
+		StatusName:  statusName(t.Status),
+		Section:     t.Section,
+		Tags:        t.Tags,
+		Contexts:    t.Contexts,
+		Projects:    t.Projects,
+		Priority:    t.Priority,
+	}
+	if !t.Due.IsZero() {
+		a.Due = t.Due.Format("2006-01-02")
+	}
+	if !t.Scheduled.IsZero() {
+		a.Scheduled = t.Scheduled.Format("2006-01-02")
+	}
+	if !t.At.IsZero() {
+		a.At = t.At.Format("15:04")
+	}
+	return a
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	verbosef("%d: %v", code, err)
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// decodeWrite reads the JSON body of a request which changes today.md. Browsers only send cross-site requests
+// with a JSON content type after a CORS preflight (which is never allowed), so requiring one, along with a same
+// origin, stops other web pages from changing tasks
+func decodeWrite(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || ct != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("the content type must be application/json"))
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			writeError(w, http.StatusForbidden, fmt.Errorf("cross-origin requests are not allowed"))
+			return false
+		}
+	}
+	if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+		writeError(w, http.StatusForbidden, fmt.Errorf("cross-origin requests are not allowed"))
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if err == io.EOF {
+			err = fmt.Errorf("a JSON body is required")
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// GET lists tasks, filtered as per `today list` (e.g. ?tag=home&status=x). POST adds one
+func (s *server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := loadToday()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		f := &taskFilter{tags: q["tag"], contexts: q["context"], projects: q["project"], statuses: q["status"], group: "section"}
+		if err := f.validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		ts := flatten(t.Tasks())
+		ret := []apiTask{}
+		for i, id := range taskIDs(ts) {
+			if f.match(ts[i]) {
+				ret = append(ret, toAPITask(id, ts[i]))
+			}
+		}
+		writeJSON(w, http.StatusOK, ret)
+	case http.MethodPost:
+		var req struct {
+			Description string `json:"description"`
+			Section     string `json:"section"`
+			Status      string `json:"status"`
+		}
+		if !decodeWrite(w, r, &req) {
+			return
+		}
+		req.Description = strings.TrimSpace(req.Description)
+		if req.Description == "" || strings.Contains(req.Description, "\n") {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("a description (one line) is required"))
+			return
+		}
+		if req.Section == "" {
+			req.Section = "Inbox"
+		}
+		status, ok := statusKey(req.Status)
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown status '%s'", req.Status))
+			return
+		}
+		item := itemNode(t.SectionList(req.Section), "["+status+"] "+req.Description)
+		if err := newFile(t.file, t); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.find(t, item))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed", r.Method))
+	}
+}
+
+// find the task for an item, with its ID
+func (s *server) find(t tasks, item *blackfriday.Node) apiTask {
+	ts := flatten(t.Tasks())
+	for i, id := range taskIDs(ts) {
+		if ts[i].node == item {
+			return toAPITask(id, ts[i])
+		}
+	}
+	return apiTask{}
+}
+
+// GET or PATCH /tasks/{id}. A PATCH may change the status
+func (s *server) handleTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strings.TrimPrefix(r.URL.Path, "/tasks/")
+	t, err := loadToday()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var (
+		ts    = flatten(t.Tasks())
+		found = -1
+	)
+	for i, tid := range taskIDs(ts) {
+		if tid == id {
+			found = i
+			break
+		}
+	}
+	if found < 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no task '%s'", id))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, toAPITask(id, ts[found]))
+	case http.MethodPatch:
+		var req struct {
+			Status *string `json:"status"`
+		}
+		if !decodeWrite(w, r, &req) {
+			return
+		}
+		if req.Status != nil {
+			status, ok := statusKey(*req.Status)
+			if !ok {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("unknown status '%s'", *req.Status))
+				return
+			}
+			setStatus(ts[found].node, status)
+			if err := newFile(t.file, t); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, s.find(t, ts[found].node))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed", r.Method))
+	}
+}
+
+func (s *server) handleRollover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed", r.Method))
+		return
+	}
+	// e.g. {}, so that only a script (and not a form on another site) can roll over
+	var req struct{}
+	if !decodeWrite(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := loadToday()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if d, err := getDateHeader(old); err == nil && !day(time.Now()).After(d) {
+		writeError(w, http.StatusConflict, fmt.Errorf("same day - no rollover"))
+		return
+	}
+	if err := prune([]string{"rollover"}, true, false); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"rolledOver": time.Now().Format("2006-01-02")})
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>.task-list-item { list-style-type: none }</style>
+</head>
+<body>
+<nav><a href="/">Today</a> | <a href="/archives">Archives</a></nav>
+{{.Body}}
+</body>
+</html>
+`))
+
+// writeHTML renders markdown with blackfriday's HTML renderer, read only
+func writeHTML(w http.ResponseWriter, title string, t tasks) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = writeHTMLPage(w, title, t)
+}
+
+// writeHTMLPage renders a document as a page, with its tasks as GFM checkboxes
+func writeHTMLPage(w io.Writer, title string, t tasks) error {
+	escapeHTML(t.node)
+	toGFM(t.node, true)
+	var b strings.Builder
+	render(newTaskListRenderer(), &b, t.node)
+	return pageTemplate.Execute(w, map[string]interface{}{"Title": title, "Body": template.HTML(b.String())})
+}
+
+func (s *server) handleToday(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	t, err := loadToday()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeHTML(w, t.GetFirstHeadingText(), t)
+}
+
+func (s *server) handleArchives(w http.ResponseWriter, r *http.Request) {
+	dates, err := getArchiveDates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	doc := blackfriday.NewNode(blackfriday.Document)
+	headingNode(doc, 1, "Archives")
+	list := listNode(doc)
+	for i := len(dates) - 1; i >= 0; i-- {
+		d := dates[i].Format("2006-01-02")
+		item := itemNode(list, "")
+		link := blackfriday.NewNode(blackfriday.Link)
+		link.LinkData.Destination = []byte("/archives/" + d)
+		text := blackfriday.NewNode(blackfriday.Text)
+		text.Literal = []byte(dates[i].Format("2006-01-02, Monday"))
+		link.AppendChild(text)
+		item.FirstChild.AppendChild(link)
+	}
+	writeHTML(w, "Archives", tasks{node: doc})
+}
+
+// handleArchive shows /archives/yyyy-mm-dd
+func (s *server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	d, err := time.ParseInLocation("2006-01-02", strings.TrimPrefix(r.URL.Path, "/archives/"), time.Local)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	fa, err := getArchiveFilename(d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	t, err := parseFile(fa)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeHTML(w, filepath.Base(fa), t)
+}
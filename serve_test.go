@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteHTMLPageEscapes(t *testing.T) {
+	tt, err := parse([]byte("# 2026-10-19, Monday\n\n## Inbox\n\n- [x] done <script>alert(1)</script>\n- [ ] <img src=x onerror=alert(2)>\n\n<div onclick=\"alert(3)\">block</div>\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := writeHTMLPage(&b, "Today", tt); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, raw := range []string{"<script>", "<img", "<div"} {
+		if strings.Contains(out, raw) {
+			t.Errorf("%s is not escaped:\n%s", raw, out)
+		}
+	}
+	for _, escaped := range []string{"&lt;script&gt;alert(1)&lt;/script&gt;", "&lt;img src=x onerror=alert(2)&gt;", "&lt;div onclick="} {
+		if !strings.Contains(out, escaped) {
+			t.Errorf("expected %s in:\n%s", escaped, out)
+		}
+	}
+	if strings.Count(out, `class="task-list-item-checkbox"`) != 2 {
+		t.Errorf("expected 2 checkboxes in:\n%s", out)
+	}
+}
+
+func TestAllowedHost(t *testing.T) {
+	for _, tc := range []struct {
+		host, addr string
+		expected   bool
+	}{
+		{"127.0.0.1:8080", "127.0.0.1:8080", true},
+		{"localhost:8080", "127.0.0.1:8080", true},
+		{"[::1]:8080", "127.0.0.1:8080", true},
+		{"192.168.1.2:8080", ":8080", true},
+		{"myhost:8080", "myhost:8080", true},
+		{"evil.example.com:8080", "127.0.0.1:8080", false},
+		{"evil.example.com:8080", ":8080", false},
+		{"127.0.0.1:9090", "127.0.0.1:8080", false},
+		{"127.0.0.1", "127.0.0.1:8080", false},
+		{"localhost", "localhost:80", true},
+	} {
+		if got := allowedHost(tc.host, tc.addr); got != tc.expected {
+			t.Errorf("allowedHost(%s, %s) is %v, expected %v", tc.host, tc.addr, got, tc.expected)
+		}
+	}
+}
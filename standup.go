@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/laher/markdownfmt/markdown"
+	"github.com/russross/blackfriday/v2"
+)
+
+var standupFormats = []string{"text", "markdown", "slack"}
+
+// slackEmoji are shown against each status in the slack format
+var slackEmoji = map[string]string{
+	" ": ":white_medium_square:",
+	"i": ":hourglass_flowing_sand:",
+	"x": ":white_check_mark:",
+	"p": ":double_vertical_bar:",
+	"c": ":x:",
+}
+
+func validStandupFormat(f string) bool {
+	for _, v := range standupFormats {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+// standupCmd summarises the most recent archive and today.md, ready to paste into chat
+func standupCmd(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	def := cfg.Standup.Format
+	if def == "" {
+		def = "text"
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	format := fs.String("format", def, "one of "+strings.Join(standupFormats, ", "))
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if !validStandupFormat(*format) {
+		return fmt.Errorf("unknown format '%s' (expected one of %s)", *format, strings.Join(standupFormats, ", "))
+	}
+	base, err := getBaseDir()
+	if err != nil {
+		return err
+	}
+	s, err := loadStandup(listName(), base, day(time.Now()))
+	if err != nil {
+		return err
+	}
+	// in progress first, then the rest of the plan
+	s.open = append(withStatus(s.open, "i"), withStatus(s.open, " ")...)
+	switch *format {
+	case "markdown":
+		render(markdown.NewRenderer(&markdown.Options{Terminal: false, HashHeaders: true}), os.Stdout, standupDoc(s).node)
+	case "slack":
+		writeSlackStandup(os.Stdout, s)
+	default:
+		writeTextStandup(os.Stdout, s)
+	}
+	return nil
+}
+
+func yesterdayTitle(s standup) string {
+	if s.yesterday.IsZero() {
+		return "Yesterday"
+	}
+	return "Yesterday (" + s.yesterday.Format("Monday 2006-01-02") + ")"
+}
+
+func standupDoc(s standup) tasks {
+	doc := blackfriday.NewNode(blackfriday.Document)
+	headingNode(doc, 2, yesterdayTitle(s))
+	standupList(doc, s.closed)
+	headingNode(doc, 2, "Today")
+	standupList(doc, s.open)
+	return tasks{node: doc}
+}
+
+func writeTextStandup(w io.Writer, s standup) {
+	section := func(title string, ts []task) {
+		fmt.Fprintln(w, title+":")
+		if len(ts) == 0 {
+			fmt.Fprintln(w, "  nothing")
+		}
+		for _, t := range ts {
+			fmt.Fprintf(w, "  %s: %s\n", strings.ToLower(statusName(t.Status)), t.Description)
+		}
+	}
+	section(yesterdayTitle(s), s.closed)
+	section("Today", s.open)
+}
+
+// writeSlackStandup writes Slack's mrkdwn
+func writeSlackStandup(w io.Writer, s standup) {
+	section := func(title string, ts []task) {
+		fmt.Fprintf(w, "*%s*\n", title)
+		if len(ts) == 0 {
+			fmt.Fprintln(w, "• _nothing_")
+		}
+		for _, t := range ts {
+			e, ok := slackEmoji[t.Status]
+			if !ok {
+				e = "[" + t.Status + "]"
+			}
+			fmt.Fprintf(w, "• %s %s\n", e, t.Description)
+		}
+	}
+	section(yesterdayTitle(s), s.closed)
+	section("Today", s.open)
+}
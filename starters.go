@@ -0,0 +1,62 @@
+package main
+
+import "sort"
+
+// starters are the starter sets for `today init --template`, as recurring.md
+var starters = map[string]string{
+	"empty": `# Recurring tasks
+
+## Daily
+
+## Weekly
+
+## Weekdays
+`,
+	"personal": `# Recurring tasks
+
+## Daily
+
+- [ ] Review today.md
+- [ ] Exercise
+- [ ] Read for 20 minutes
+
+## Weekly
+
+- [ ] Plan the week every:monday
+- [ ] Laundry every:saturday
+- [ ] Groceries every:sunday
+
+## Weekdays
+
+## Other
+
+- [ ] Pay bills every:month on the 1st
+- [ ] Back up photos after-done:3m
+`,
+	"work": `# Recurring tasks
+
+## Daily
+
+- [ ] Triage email and chat
+- [ ] Review today.md and plan the day
+
+## Weekly
+
+- [ ] Write weekly update every:friday
+- [ ] Clear the review queue every:monday
+
+## Weekdays
+
+- [ ] Standup every:weekday at:09:30
+- [ ] Update timesheet every:weekday at:17:00
+`,
+}
+
+func starterNames() []string {
+	names := []string{}
+	for name := range starters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,20 +1,36 @@
 package main
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
 
 type task struct {
 	Description string
 	Status      string
-	Tags        []string
+	Section     string
+	List        string   // the named list, when tasks from several lists are combined
+	Tags        []string // #tag
+	Contexts    []string // @context
+	Projects    []string // +project
+	Priority    int      // 1 is the highest, from (A), p1 or !!!. 0 for none
+	Due         time.Time
+	Scheduled   time.Time // not to appear in today.md before this day
+	At          time.Time // a time of day, on the day of the file
 	Created     time.Time
 	Updated     time.Time
 	Completed   time.Time
 
 	Subtasks []tasks
 
-	RecurType recurType
-	From      time.Time
-	Until     time.Time
+	RecurType  recurType
+	Recurrence *recurrence // from `every: ...` or `rrule:...`
+	From       time.Time
+	Until      time.Time
+
+	node *blackfriday.Node // the list item
 }
 
 type recurType string
@@ -26,3 +42,130 @@ const (
 	weekly   recurType = "weekly"
 	weekdays recurType = "weekdays"
 )
+
+// parseTask reads a list item of the form `[x] description`. Items without a status marker are not tasks.
+// Relative dates are resolved against ref
+func parseTask(item *blackfriday.Node, ref time.Time) (task, bool) {
+	_, status, ok := itemMarker(item)
+	if !ok {
+		return task{}, false
+	}
+	p := item.FirstChild
+	t := task{
+		node:        item,
+		Status:      status,
+		Description: strings.TrimSpace(inlineText(p)[3:]),
+	}
+	t.parseTokens(p, ref)
+	t.Priority, _ = splitPriority(t.Description)
+	if r, _, err := parseRecurrence(inlineText(p)); r != nil && err == nil {
+		t.Recurrence = r
+		t.RecurType = r.Freq
+	}
+	for c := p.Next; c != nil; c = c.Next {
+		if c.Type == blackfriday.List {
+			t.Subtasks = append(t.Subtasks, tasks{node: c, ref: ref})
+		}
+	}
+	return t, true
+}
+
+// parseStatus returns the status key (as used in `statuses`) from a leading marker such as `[x]`
+func parseStatus(content string) (string, bool) {
+	if len(content) < 3 || content[0] != '[' || content[2] != ']' {
+		return "", false
+	}
+	if len(content) > 3 && content[3] != ' ' {
+		return "", false
+	}
+	return strings.ToLower(content[1:2]), true
+}
+
+// parseTokens finds #tags, @contexts and +projects in the text of a paragraph, as per todo.txt conventions,
+// along with dates such as `due:2026-10-20`, `scheduled:fri`, `created:2026-10-01` or `📅 2026-10-20`.
+// Code spans are skipped
+func (t *task) parseTokens(p *blackfriday.Node, ref time.Time) {
+	t.Tags, t.Contexts, t.Projects = []string{}, []string{}, []string{}
+	var dateFor *time.Time // set by an emoji, for the next word
+	setDate := func(d *time.Time, s string) {
+		if v, err := parseDate(s, ref); err == nil {
+			*d = v
+		}
+	}
+	p.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || node.Type != blackfriday.Text {
+			return blackfriday.GoToNext
+		}
+		for _, word := range strings.Fields(string(node.Literal)) {
+			if dateFor != nil {
+				setDate(dateFor, word)
+				dateFor = nil
+				continue
+			}
+			switch {
+			case strings.HasPrefix(word, dueEmoji):
+				dateFor = &t.Due
+			case strings.HasPrefix(word, scheduledEmoji):
+				dateFor = &t.Scheduled
+			case strings.HasPrefix(word, "due:"):
+				setDate(&t.Due, word[len("due:"):])
+			case strings.HasPrefix(word, "scheduled:"):
+				setDate(&t.Scheduled, word[len("scheduled:"):])
+			case strings.HasPrefix(word, "at:"):
+				if mins, ok := parseClock(word[len("at:"):]); ok {
+					t.At = time.Date(ref.Year(), ref.Month(), ref.Day(), mins/60, mins%60, 0, 0, time.Local)
+				}
+			case strings.HasPrefix(word, "created:"):
+				setDate(&t.Created, word[len("created:"):])
+			case strings.HasPrefix(word, "completed:"):
+				setDate(&t.Completed, word[len("completed:"):])
+			case strings.HasPrefix(word, "from:"):
+				setDate(&t.From, word[len("from:"):])
+			case strings.HasPrefix(word, "until:"):
+				setDate(&t.Until, word[len("until:"):])
+			}
+			if dateFor != nil {
+				// e.g. 📅2026-10-20
+				if rest := strings.TrimLeft(word, dueEmoji+scheduledEmoji); rest != "" {
+					setDate(dateFor, rest)
+					dateFor = nil
+				}
+				continue
+			}
+			word = strings.TrimRight(word, ".,;:!?)")
+			if len(word) < 2 {
+				continue
+			}
+			switch word[0] {
+			case '#':
+				t.Tags = append(t.Tags, word[1:])
+			case '@':
+				t.Contexts = append(t.Contexts, word[1:])
+			case '+':
+				t.Projects = append(t.Projects, word[1:])
+			}
+		}
+		return blackfriday.GoToNext
+	})
+}
+
+// IsClosed reports whether the task is done or cancelled
+func (t task) IsClosed() bool {
+	return t.Status == "x" || t.Status == "c"
+}
+
+// flatten returns the tasks along with all their subtasks, depth first
+func flatten(ts []task) []task {
+	ret := []task{}
+	for _, t := range ts {
+		ret = append(ret, t)
+		for _, s := range t.Subtasks {
+			sub := flatten(s.Tasks())
+			for i := range sub {
+				sub[i].Section = t.Section
+			}
+			ret = append(ret, sub...)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/laher/markdownfmt/markdown"
+	"github.com/russross/blackfriday/v2"
+)
+
+// gfmFormats are the outputs of the render command
+var gfmFormats = []string{"gfm", "html"}
+
+// itemMarker finds the status marker of a task list item, such as `[ ]`, `[X]` or `[i]`, in the item's first text
+func itemMarker(item *blackfriday.Node) (*blackfriday.Node, string, bool) {
+	p := item.FirstChild
+	if item.Type != blackfriday.Item || p == nil || p.Type != blackfriday.Paragraph {
+		return nil, "", false
+	}
+	text := p.FirstChild
+	if text == nil || text.Type != blackfriday.Text {
+		return nil, "", false
+	}
+	status, ok := parseStatus(string(text.Literal))
+	return text, status, ok
+}
+
+// repairMarkers undoes blackfriday reading a marker followed by brackets, e.g. `[i] (A) Ship it`, as a link.
+// It runs as part of parse, so that such items are still tasks
+func repairMarkers(doc *blackfriday.Node) {
+	doc.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || node.Type != blackfriday.Item {
+			return blackfriday.GoToNext
+		}
+		p := node.FirstChild
+		if p == nil || p.Type != blackfriday.Paragraph {
+			return blackfriday.GoToNext
+		}
+		text := p.FirstChild
+		if text == nil || text.Type != blackfriday.Text || len(text.Literal) > 0 {
+			return blackfriday.GoToNext
+		}
+		link := text.Next
+		if link == nil || link.Type != blackfriday.Link || link.FirstChild == nil || link.FirstChild != link.LastChild ||
+			link.FirstChild.Type != blackfriday.Text {
+			return blackfriday.GoToNext
+		}
+		marker := "[" + string(link.FirstChild.Literal) + "]"
+		if _, ok := parseStatus(marker); !ok {
+			return blackfriday.GoToNext
+		}
+		dest := string(link.LinkData.Destination)
+		if len(link.LinkData.Title) > 0 {
+			dest += ` "` + string(link.LinkData.Title) + `"`
+		}
+		text.Literal = []byte(marker + " (" + dest + ")")
+		link.Unlink()
+		if next := text.Next; next != nil && next.Type == blackfriday.Text {
+			text.Literal = append(text.Literal, next.Literal...)
+			next.Unlink()
+		}
+		return blackfriday.GoToNext
+	})
+}
+
+// gfmMarker is the GFM checkbox for a status, which is only checked or not. Closed tasks are checked
+func gfmMarker(status string) string {
+	if status == "x" || status == "c" {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// toGFM rewrites the status markers of a document as GFM checkboxes. Statuses other than todo and done
+// are annotated with their name, e.g. `[ ] Ship it *(In progress)*`.
+// For HTML, the checkboxes become disabled inputs, as GitHub renders them
+func toGFM(doc *blackfriday.Node, asHTML bool) {
+	doc.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering {
+			return blackfriday.GoToNext
+		}
+		text, status, ok := itemMarker(node)
+		if !ok {
+			return blackfriday.GoToNext
+		}
+		if status != " " && status != "x" {
+			annotation := blackfriday.NewNode(blackfriday.Emph)
+			annotation.AppendChild(textNode("(" + statusName(status) + ")"))
+			appendText(node, " ")
+			text.Parent.AppendChild(annotation)
+		}
+		if !asHTML {
+			text.Literal = append([]byte(gfmMarker(status)), text.Literal[3:]...)
+			return blackfriday.GoToNext
+		}
+		checkbox := blackfriday.NewNode(blackfriday.HTMLSpan)
+		checked := ""
+		if gfmMarker(status) == "[x]" {
+			checked = " checked"
+		}
+		checkbox.Literal = []byte(fmt.Sprintf(`<input type="checkbox" class="task-list-item-checkbox" disabled%s data-status="%s">`,
+			checked, html.EscapeString(status)))
+		text.InsertBefore(checkbox)
+		text.Literal = text.Literal[3:]
+		return blackfriday.GoToNext
+	})
+}
+
+// escapeHTML turns raw HTML in a document into text, so that it is shown rather than run.
+// Task text may come from elsewhere, e.g. by import
+func escapeHTML(doc *blackfriday.Node) {
+	raw := []*blackfriday.Node{}
+	doc.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && (node.Type == blackfriday.HTMLSpan || node.Type == blackfriday.HTMLBlock) {
+			raw = append(raw, node)
+		}
+		return blackfriday.GoToNext
+	})
+	for _, n := range raw {
+		text := textNode(string(n.Literal))
+		if n.Type == blackfriday.HTMLBlock {
+			p := blackfriday.NewNode(blackfriday.Paragraph)
+			p.AppendChild(text)
+			text = p
+		}
+		n.InsertBefore(text)
+		n.Unlink()
+	}
+}
+
+// taskListRenderer is blackfriday's HTML renderer, with a class on task list items, as GitHub has
+type taskListRenderer struct {
+	*blackfriday.HTMLRenderer
+}
+
+func newTaskListRenderer() taskListRenderer {
+	return taskListRenderer{blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{Flags: blackfriday.CommonHTMLFlags})}
+}
+
+func (r taskListRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	if entering && node.Type == blackfriday.Item {
+		if p := node.FirstChild; p != nil && p.FirstChild != nil && p.FirstChild.Type == blackfriday.HTMLSpan &&
+			strings.Contains(string(p.FirstChild.Literal), "task-list-item-checkbox") {
+			io.WriteString(w, `<li class="task-list-item">`)
+			return blackfriday.GoToNext
+		}
+	}
+	return r.HTMLRenderer.RenderNode(w, node, entering)
+}
+
+// renderCmd writes today.md (or another file) for other markdown tools, with GFM checkboxes
+func renderCmd(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	format := fs.String("format", "gfm", "one of "+strings.Join(gfmFormats, ", "))
+	out := fs.String("o", "", "write to this file, instead of stdout")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *format != "gfm" && *format != "html" {
+		return fmt.Errorf("unknown format '%s' (expected one of %s)", *format, strings.Join(gfmFormats, ", "))
+	}
+	var (
+		t   tasks
+		err error
+	)
+	if fs.NArg() > 0 {
+		t, err = parseFile(fs.Arg(0))
+	} else {
+		t, err = loadToday()
+	}
+	if err != nil {
+		return err
+	}
+	write := func(w io.Writer) error {
+		if *format == "html" {
+			return writeHTMLPage(w, t.GetFirstHeadingText(), t)
+		}
+		toGFM(t.node, false)
+		render(markdown.NewRenderer(&markdown.Options{Terminal: false, HashHeaders: true}), w, t.node)
+		return nil
+	}
+	if *out == "" {
+		return write(os.Stdout)
+	}
+	fh, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := write(fh); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/laher/markdownfmt/markdown"
+)
+
+func TestRepairMarkers(t *testing.T) {
+	for _, tc := range []struct {
+		item        string
+		isTask      bool
+		status      string
+		description string
+	}{
+		{"- [i] (A) Ship it", true, "i", "(A) Ship it"},
+		{"- [ ] (B) Call mum", true, " ", "(B) Call mum"},
+		{`- [x] (https://example.com "Docs") Read`, true, "x", `(https://example.com "Docs") Read`},
+		{"- [X] Done", true, "x", "Done"},
+		{"- [docs](https://example.com)", false, "", ""},
+		{"- Call mum", false, "", ""},
+	} {
+		t.Run(tc.item, func(t *testing.T) {
+			tt, err := parse([]byte("## Inbox\n\n" + tc.item + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ts := tt.Tasks()
+			switch {
+			case !tc.isTask && len(ts) != 0:
+				t.Errorf("expected no task, got %+v", ts[0])
+			case tc.isTask && len(ts) != 1:
+				t.Errorf("expected a task, got %d", len(ts))
+			case tc.isTask && (ts[0].Status != tc.status || ts[0].Description != tc.description):
+				t.Errorf("got [%s] %s, expected [%s] %s", ts[0].Status, ts[0].Description, tc.status, tc.description)
+			}
+		})
+	}
+}
+
+func TestRepairMarkersSubtasks(t *testing.T) {
+	tt, err := parse([]byte("## Inbox\n\n- [ ] Parent\n    - [i] (B) Child\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := flatten(tt.Tasks())
+	if len(ts) != 2 || ts[1].Status != "i" || ts[1].Description != "(B) Child" || ts[1].Priority != 2 {
+		t.Errorf("expected the subtask [i] (B) Child, got %+v", ts)
+	}
+}
+
+func TestToGFM(t *testing.T) {
+	for _, tc := range []struct {
+		item string
+		gfm  string
+		html string
+	}{
+		{"- [i] (A) Ship it", "[ ] (A) Ship it *(In progress)*",
+			`<li class="task-list-item"><input type="checkbox" class="task-list-item-checkbox" disabled data-status="i"> (A) Ship it <em>(In progress)</em></li>`},
+		{"- [X] Done", "[x] Done",
+			`<li class="task-list-item"><input type="checkbox" class="task-list-item-checkbox" disabled checked data-status="x"> Done</li>`},
+		{"- [c] Cancelled", "[x] Cancelled *(Cancelled)*", `checked data-status="c"> Cancelled <em>(Cancelled)</em>`},
+		{"- [docs](https://example.com)", "[docs](https://example.com)", `<li><a href="https://example.com">docs</a></li>`},
+		{"- [ ] Parent\n    - [i] (B) Child", "\t-\t[ ] (B) Child *(In progress)*",
+			`<ul>
+<li class="task-list-item"><input type="checkbox" class="task-list-item-checkbox" disabled data-status="i"> (B) Child <em>(In progress)</em></li>
+</ul>`},
+	} {
+		t.Run(tc.item, func(t *testing.T) {
+			src := []byte("# 2026-10-19, Monday\n\n## Inbox\n\n" + tc.item + "\n")
+			tt, err := parse(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			toGFM(tt.node, false)
+			var b bytes.Buffer
+			render(markdown.NewRenderer(&markdown.Options{Terminal: false, HashHeaders: true}), &b, tt.node)
+			if !strings.Contains(b.String(), tc.gfm) {
+				t.Errorf("expected %q in the GFM:\n%s", tc.gfm, b.String())
+			}
+			if tt, err = parse(src); err != nil {
+				t.Fatal(err)
+			}
+			var h strings.Builder
+			if err := writeHTMLPage(&h, "Today", tt); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(h.String(), tc.html) {
+				t.Errorf("expected %q in the HTML:\n%s", tc.html, h.String())
+			}
+		})
+	}
+}
@@ -2,17 +2,53 @@ package main
 
 import (
 	"strings"
+	"time"
 
 	"github.com/russross/blackfriday/v2"
 )
 
 type tasks struct {
 	node *blackfriday.Node
+	ref  time.Time // relative dates are resolved against this, or else the date heading, or else today
+
+	file string // where it was parsed from, if anywhere
+	src  []byte // the source, for line numbers
 }
 
+// Tasks returns the top-level tasks, each labelled with the heading it sits under.
+// Nested tasks are available via Subtasks
 func (t tasks) Tasks() []task {
-	// TODO use AST
-	return []task{}
+	var (
+		ret     = []task{}
+		section = ""
+		ref     = t.ref
+	)
+	if ref.IsZero() {
+		if d, ok := t.headingDate(); ok {
+			ref = d
+		} else {
+			ref = time.Now()
+		}
+	}
+	add := func(item *blackfriday.Node) {
+		if tk, ok := parseTask(item, ref); ok {
+			tk.Section = section
+			ret = append(ret, tk)
+		}
+	}
+	for n := t.node.FirstChild; n != nil; n = n.Next {
+		switch n.Type {
+		case blackfriday.Heading:
+			section = inlineText(n)
+		case blackfriday.List:
+			for item := n.FirstChild; item != nil; item = item.Next {
+				add(item)
+			}
+		case blackfriday.Item:
+			add(n)
+		}
+	}
+	return ret
 }
 
 func (t tasks) GetFirstHeadingText() string {
@@ -41,6 +77,52 @@ func (t tasks) GetFirstHeadingText() string {
 	return ret
 }
 
+// SectionList returns the first list in the section under the given heading, adding the list (and heading) if need be
+func (t tasks) SectionList(heading string) *blackfriday.Node {
+	var h *blackfriday.Node
+	for n := t.node.FirstChild; n != nil; n = n.Next {
+		if n.Type == blackfriday.Heading && inlineText(n) == heading {
+			h = n
+			break
+		}
+	}
+	if h == nil {
+		h = headingNode(t.node, 2, heading)
+	}
+	for n := h.Next; n != nil; n = n.Next {
+		if n.Type == blackfriday.Heading && n.Level <= h.Level {
+			l := newListNode()
+			n.InsertBefore(l)
+			return l
+		}
+		if n.Type == blackfriday.List {
+			return n
+		}
+	}
+	return listNode(t.node)
+}
+
+// Section is the content under the heading with exactly this text (unlike ByHeader)
+func (t tasks) Section(heading string) []*blackfriday.Node {
+	ret := []*blackfriday.Node{}
+	level := 0
+	for n := t.node.FirstChild; n != nil; n = n.Next {
+		if n.Type == blackfriday.Heading {
+			if level > 0 && n.Level <= level {
+				break
+			}
+			if level == 0 && inlineText(n) == heading {
+				level = n.Level
+				continue
+			}
+		}
+		if level > 0 {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
+
 func (t tasks) ByHeader(s string) []*blackfriday.Node {
 	var candidates = []*blackfriday.Node{}
 	inLevel := -1 // grab everything
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// twTask is a task as in Taskwarrior's `task export` and `task import` (see https://taskwarrior.org/docs/design/task/).
+// Statuses without an equivalent are kept in a today_status attribute, which Taskwarrior keeps as an orphaned UDA
+type twTask struct {
+	UUID        string   `json:"uuid"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Entry       string   `json:"entry,omitempty"`
+	Modified    string   `json:"modified,omitempty"`
+	Start       string   `json:"start,omitempty"`
+	End         string   `json:"end,omitempty"`
+	Due         string   `json:"due,omitempty"`
+	Scheduled   string   `json:"scheduled,omitempty"`
+	Wait        string   `json:"wait,omitempty"`
+	Until       string   `json:"until,omitempty"`
+	Recur       string   `json:"recur,omitempty"`
+	Parent      string   `json:"parent,omitempty"`
+	Project     string   `json:"project,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	TodayStatus string   `json:"today_status,omitempty"`
+}
+
+const twTimeFormat = "20060102T150405Z"
+
+// twPriorities maps priorities to Taskwarrior's. Lower priorities are L
+var twPriorities = map[int]string{1: "H", 2: "M", 3: "L"}
+
+// twRecur is a Taskwarrior duration, such as `weekly` or `3d`
+var twRecur = regexp.MustCompile(`^(\d*)\s*([a-z]+)$`)
+
+func twTime(d time.Time) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.UTC().Format(twTimeFormat)
+}
+
+// twUUID is a version 5 style UUID, from a task's uid
+func twUUID(uid string) string {
+	b := sha1.Sum([]byte(uid))
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// writeTaskwarrior writes a JSON array, as `task export` does. Recurring tasks are written as templates,
+// when their rule is a simple interval on a calendar
+func writeTaskwarrior(w io.Writer, ts []exportTask) error {
+	out := []twTask{}
+	now := time.Now()
+	for _, t := range ts {
+		tw := twTask{
+			UUID:     twUUID(t.uid),
+			Status:   "pending",
+			Entry:    twTime(t.Created),
+			Modified: twTime(now),
+			Tags:     t.Tags,
+		}
+		if tw.Entry == "" {
+			tw.Entry = twTime(day(now))
+		}
+		tw.Description = plainDescription(t.Description)
+		switch {
+		case t.Priority > 3:
+			tw.Priority = twPriorities[3]
+		case t.Priority > 0:
+			tw.Priority = twPriorities[t.Priority]
+		}
+		if len(t.Projects) > 0 {
+			tw.Project = t.Projects[0]
+		}
+		switch t.Status {
+		case " ":
+		case "i":
+			tw.Start = twTime(now)
+		case "x":
+			tw.Status, tw.End = "completed", twTime(t.Completed)
+		case "c":
+			tw.Status, tw.End = "deleted", twTime(t.Completed)
+		default:
+			tw.TodayStatus = t.Status
+		}
+		if tw.End == "" && tw.Status != "pending" {
+			tw.End = tw.Modified
+		}
+		if !t.Due.IsZero() {
+			due := t.Due
+			if !t.At.IsZero() {
+				due = time.Date(due.Year(), due.Month(), due.Day(), t.At.Hour(), t.At.Minute(), 0, 0, time.Local)
+			}
+			tw.Due = twTime(due)
+		} else if !t.At.IsZero() && t.Recurrence == nil {
+			tw.Due = twTime(t.At)
+		}
+		tw.Scheduled = twTime(t.Scheduled)
+		if r := t.Recurrence; r != nil {
+			recur, ok := twRecurrence(r)
+			if !ok {
+				verbosef("skipping '%s', as Taskwarrior can't express %s", t.Description, r)
+				continue
+			}
+			// a template needs a due date, from which its instances are generated
+			start := t.from
+			if next := r.next(now, t.from, t.until, 1); len(next) > 0 {
+				start = next[0]
+			}
+			if len(r.Times) > 0 {
+				start = start.Add(time.Duration(r.Times[0]) * time.Minute)
+			}
+			tw.Status, tw.Recur, tw.Due, tw.Until = "recurring", recur, twTime(start), twTime(t.until)
+		}
+		out = append(out, tw)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// twRecurrence is a rule as a recur value. Taskwarrior recurs on a calendar, from the due date
+func twRecurrence(r *recurrence) (string, bool) {
+	if r.AfterDone || len(r.ByMonthDay) > 1 || len(r.ByMonth) > 1 || len(r.Times) > 1 {
+		return "", false
+	}
+	if isWeekdays(r) {
+		return "weekdays", true
+	}
+	if len(r.ByDay) > 1 || len(r.ByDay) == 1 && (r.ByDay[0].N != 0 || r.Freq != weekly) {
+		return "", false
+	}
+	if r.Interval == 1 {
+		return string(r.Freq), true
+	}
+	unit, ok := map[recurType]string{daily: "d", weekly: "w", monthly: "mo", yearly: "y"}[r.Freq]
+	return strconv.Itoa(r.Interval) + unit, ok
+}
+
+// isWeekdays reports whether a rule is every Monday to Friday
+func isWeekdays(r *recurrence) bool {
+	if r.Freq != weekly || r.Interval != 1 || len(r.ByDay) != len(mondayToFriday) {
+		return false
+	}
+	for i, d := range r.ByDay {
+		if d != mondayToFriday[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTwRecur reads a recur value, such as `weekly`, `2w` or `P3D`
+func parseTwRecur(s string) (*recurrence, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "weekdays":
+		return &recurrence{Freq: weekly, Interval: 1, ByDay: mondayToFriday}, nil
+	case "biweekly", "fortnight":
+		return &recurrence{Freq: weekly, Interval: 2}, nil
+	case "quarterly":
+		return &recurrence{Freq: monthly, Interval: 3}, nil
+	case "semiannual":
+		return &recurrence{Freq: monthly, Interval: 6}, nil
+	case "annual":
+		return &recurrence{Freq: yearly, Interval: 1}, nil
+	}
+	if strings.HasPrefix(s, "p") && !strings.Contains(s, "t") {
+		// ISO 8601, e.g. P3D
+		s = s[1:]
+	}
+	m := twRecur.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("recur '%s' is not supported", s)
+	}
+	n := 1
+	if m[1] != "" {
+		n, _ = strconv.Atoi(m[1])
+	}
+	r := &recurrence{Interval: n}
+	switch m[2] {
+	case "d", "day", "days", "daily":
+		r.Freq = daily
+	case "w", "wk", "wks", "week", "weeks", "weekly":
+		r.Freq = weekly
+	case "m", "mo", "mos", "mth", "mths", "month", "months", "monthly":
+		r.Freq = monthly
+	case "q", "qtr", "qtrs", "quarter", "quarters":
+		r.Freq, r.Interval = monthly, 3*n
+	case "y", "yr", "yrs", "year", "years", "yearly":
+		r.Freq = yearly
+	default:
+		return nil, fmt.Errorf("recur '%s' is not supported", s)
+	}
+	return r, r.validate()
+}
+
+// readTaskwarrior reads a JSON array of tasks, or a task per line. The instances of recurring tasks are skipped,
+// as their template is imported
+func readTaskwarrior(r io.Reader) ([]task, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	tws := []twTask{}
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		if err := json.Unmarshal(b, &tws); err != nil {
+			return nil, err
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		for dec.More() {
+			var tw twTask
+			if err := dec.Decode(&tw); err != nil {
+				return nil, err
+			}
+			tws = append(tws, tw)
+		}
+	}
+	ret := []task{}
+	for _, tw := range tws {
+		if tw.Parent != "" {
+			verbosef("skipping '%s', an instance of a recurring task", tw.Description)
+			continue
+		}
+		t := task{Status: " ", Tags: tw.Tags, Description: strings.Join(strings.Fields(tw.Description), " ")}
+		if t.Description == "" {
+			continue
+		}
+		var (
+			err       error
+			due, wait time.Time
+		)
+		// dates are kept as days, other than the due time
+		setTime := func(d *time.Time, s string, truncate bool) {
+			if s == "" || err != nil {
+				return
+			}
+			var v time.Time
+			if v, err = time.Parse(twTimeFormat, s); err == nil {
+				*d = v.In(time.Local)
+				if truncate {
+					*d = day(*d)
+				}
+			}
+		}
+		setTime(&t.Created, tw.Entry, true)
+		setTime(&t.Completed, tw.End, true)
+		setTime(&due, tw.Due, false)
+		setTime(&t.Scheduled, tw.Scheduled, true)
+		setTime(&wait, tw.Wait, true)
+		setTime(&t.Until, tw.Until, true)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %v", tw.Description, err)
+		}
+		if wait.After(t.Scheduled) {
+			// waiting tasks are hidden until then
+			t.Scheduled = wait
+		}
+		if !due.IsZero() {
+			t.Due = day(due)
+			if !due.Equal(t.Due) {
+				t.At = due
+			}
+		}
+		switch tw.Status {
+		case "completed":
+			t.Status = "x"
+		case "deleted":
+			t.Status = "c"
+		case "pending", "waiting":
+			if tw.Start != "" {
+				t.Status = "i"
+			}
+		case "recurring":
+			rule, err := parseTwRecur(tw.Recur)
+			if err != nil {
+				verbosef("'%s': %v, so it is imported as a one off", tw.Description, err)
+				break
+			}
+			// instances are generated from the due date
+			t.Recurrence, t.From, t.Due = rule, t.Due, time.Time{}
+		}
+		if k, ok := statusKey(tw.TodayStatus); tw.TodayStatus != "" && ok {
+			t.Status = k
+		}
+		if tw.Project != "" {
+			t.Projects = []string{tw.Project}
+		}
+		for n, p := range twPriorities {
+			if p == tw.Priority {
+				t.Priority = n
+			}
+		}
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
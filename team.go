@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/laher/markdownfmt/markdown"
+	"github.com/russross/blackfriday/v2"
+)
+
+// standup is one person's report: what they closed on their last archived day, and what is open today
+type standup struct {
+	name      string
+	yesterday time.Time // zero when there is no archive
+	closed    []task
+	open      []task
+	err       error // when there is no report, e.g. as today.md is missing
+}
+
+// team prints a standup report for everyone in the team, as configured in config.json
+func team(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if len(cfg.Team) == 0 {
+		return errors.New(`no team configured (add e.g. "team": [{"name": "alice", "dir": "/shared/alice"}] to ` + configBase + ")")
+	}
+	base, err := getBaseDir()
+	if err != nil {
+		return err
+	}
+	today := day(time.Now())
+	reports := []standup{}
+	for _, m := range cfg.Team {
+		dir := m.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(base, dir)
+		}
+		s, err := loadStandup(m.Name, dir, today)
+		if err != nil {
+			// others' reports are still worth having, in a shared checkout
+			fmt.Fprintf(os.Stderr, "[%s]: %s: %v\n", args[0], m.Name, err)
+			s = standup{name: m.Name, err: err}
+		}
+		reports = append(reports, s)
+	}
+	doc := buildStandups(fmt.Sprintf("Team standup, %s", today.Format("2006-01-02, Monday")), reports)
+	render(markdown.NewRenderer(&markdown.Options{Terminal: false, HashHeaders: true}), os.Stdout, doc.node)
+	return nil
+}
+
+// loadStandup reads the today.md in dir, and the most recent archive before today
+func loadStandup(name, dir string, today time.Time) (standup, error) {
+	s := standup{name: name}
+	t, err := parseFile(filepath.Join(dir, todayBase))
+	if err != nil {
+		return s, err
+	}
+	for _, tk := range flatten(t.Tasks()) {
+		if !tk.IsClosed() {
+			s.open = append(s.open, tk)
+		}
+	}
+	dates, err := archiveDates(dir)
+	if err != nil {
+		return s, err
+	}
+	for i := len(dates) - 1; i >= 0; i-- {
+		if dates[i].Before(today) {
+			s.yesterday = dates[i]
+			break
+		}
+	}
+	if s.yesterday.IsZero() {
+		return s, nil
+	}
+	a, err := parseFile(archiveFilename(dir, s.yesterday))
+	if err != nil {
+		return s, err
+	}
+	for _, tk := range flatten(a.Tasks()) {
+		if tk.IsClosed() {
+			s.closed = append(s.closed, tk)
+		}
+	}
+	return s, nil
+}
+
+func buildStandups(title string, reports []standup) tasks {
+	doc := blackfriday.NewNode(blackfriday.Document)
+	headingNode(doc, 1, title)
+	for _, s := range reports {
+		headingNode(doc, 2, s.name)
+		if s.err != nil {
+			textParaNode(doc, "No report.")
+			continue
+		}
+		if s.yesterday.IsZero() {
+			headingNode(doc, 3, "Done")
+			textParaNode(doc, "No archive yet.")
+		} else {
+			headingNode(doc, 3, "Done ("+s.yesterday.Format("Monday 2006-01-02")+")")
+			standupList(doc, withStatus(s.closed, "x"))
+		}
+		headingNode(doc, 3, "In progress")
+		standupList(doc, withStatus(s.open, "i"))
+	}
+	return tasks{node: doc}
+}
+
+func withStatus(ts []task, status string) []task {
+	ret := []task{}
+	for _, t := range ts {
+		if t.Status == status {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+func standupList(doc *blackfriday.Node, ts []task) {
+	if len(ts) == 0 {
+		textParaNode(doc, "Nothing.")
+		return
+	}
+	list := listNode(doc)
+	for _, t := range ts {
+		itemNode(list, fmt.Sprintf("[%s] %s", t.Status, t.Description))
+	}
+}
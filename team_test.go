@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/laher/markdownfmt/markdown"
+)
+
+func TestBuildStandupsWithoutReport(t *testing.T) {
+	reports := []standup{
+		{name: "alice", err: errors.New("open today.md: no such file or directory")},
+		{name: "bob", open: []task{{Status: "i", Description: "Write the report"}}},
+	}
+	var b bytes.Buffer
+	render(markdown.NewRenderer(&markdown.Options{Terminal: false, HashHeaders: true}), &b, buildStandups("Team standup", reports).node)
+	out := b.String()
+	alice, bob := strings.Index(out, "## alice"), strings.Index(out, "## bob")
+	if alice < 0 || bob < alice {
+		t.Fatalf("expected sections for alice, then bob:\n%s", out)
+	}
+	if !strings.Contains(out[alice:bob], "No report.") {
+		t.Errorf("expected 'No report.' for alice:\n%s", out)
+	}
+	if !strings.Contains(out[bob:], "[i] Write the report") {
+		t.Errorf("expected bob's task:\n%s", out)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/laher/markdownfmt/markdown"
+	"github.com/russross/blackfriday/v2"
+)
+
+// dayParts are the pieces of a new day, before they are laid out by template.md or by the configured sections
+type dayParts struct {
+	date      time.Time
+	overdue   []*blackfriday.Node
+	inbox     []*blackfriday.Node
+	rolled    []*blackfriday.Node
+	custom    map[string][]*blackfriday.Node
+	recurring tasks
+	done      completions
+	present   map[string]bool // open tasks, so that recurring ones aren't repeated
+}
+
+// loadTemplate loads template.md, if there is one
+func loadTemplate() (*template.Template, error) {
+	file, err := getTemplateFilename()
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the functions are replaced for each day
+	return template.New(templateBase).Funcs((&dayParts{}).funcs(nil)).Parse(string(b))
+}
+
+// recurringItems are today's items from the given sections of recurring.md (or else all of them), unless they are already present
+func (p *dayParts) recurringItems(sections ...string) []*blackfriday.Node {
+	if p.recurring.node == nil {
+		return nil
+	}
+	if p.present == nil {
+		carried := append(append(append([]*blackfriday.Node{}, p.overdue...), p.inbox...), p.rolled...)
+		for _, nodes := range p.custom {
+			carried = append(carried, nodes...)
+		}
+		p.present = openKeys(carried)
+	}
+	r := p.recurring
+	if len(sections) > 0 {
+		// a copy, as recurringFor takes the items it uses
+		doc := blackfriday.NewNode(blackfriday.Document)
+		for _, s := range sections {
+			headingNode(doc, 2, s)
+			for _, n := range p.recurring.Section(s) {
+				doc.AppendChild(cloneNode(n))
+			}
+		}
+		r = tasks{node: doc}
+	}
+	return dedupRecurring(p.present, recurringFor(r, p.date, p.done))
+}
+
+// funcs are the template functions. Any parts which are used are recorded in used
+func (p *dayParts) funcs(used map[string]bool) template.FuncMap {
+	part := func(name string, nodes []*blackfriday.Node) string {
+		used[name] = true
+		return renderNodes(nodes)
+	}
+	return template.FuncMap{
+		"date":    func() string { return p.date.Format("2006-01-02") },
+		"weekday": func() string { return p.date.Weekday().String() },
+		"overdue": func() string { return part("Overdue", p.overdue) },
+		"inbox":   func() string { return part("Inbox", p.inbox) },
+		"rolled":  func() string { return part("Rolled Over", p.rolled) },
+		"section": func(name string) string { return part(name, p.custom[name]) },
+		"recurring": func(sections ...string) string {
+			used["{{recurring}}"] = true
+			items := p.recurringItems(sections...)
+			if len(items) == 0 {
+				return ""
+			}
+			list := newListNode()
+			for _, n := range items {
+				list.AppendChild(n)
+			}
+			return renderNodes([]*blackfriday.Node{list})
+		},
+	}
+}
+
+// execute lays out the day with the template. Tasks which the template leaves out are added at the end, so that nothing is lost
+func (p *dayParts) execute(tmpl *template.Template) (tasks, error) {
+	used := map[string]bool{}
+	var b bytes.Buffer
+	if err := tmpl.Funcs(p.funcs(used)).Execute(&b, nil); err != nil {
+		return tasks{}, err
+	}
+	parts := map[string][]*blackfriday.Node{"Overdue": p.overdue, "Inbox": p.inbox, "Rolled Over": p.rolled}
+	names := []string{}
+	for name, nodes := range p.custom {
+		parts[name] = nodes
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range append([]string{"Overdue", "Inbox", "Rolled Over"}, names...) {
+		if used[name] || len(parts[name]) == 0 {
+			continue
+		}
+		verbosef("%s leaves out %s, so it is added at the end", templateBase, name)
+		b.WriteString("\n## " + name + "\n\n" + renderNodes(parts[name]))
+	}
+	if !used["{{recurring}}"] {
+		if items := p.recurringItems(); len(items) > 0 {
+			verbosef("%s leaves out {{recurring}}, so today's recurring tasks are added at the end", templateBase)
+			list := newListNode()
+			for _, n := range items {
+				list.AppendChild(n)
+			}
+			b.WriteString("\n## Daily\n\n" + renderNodes([]*blackfriday.Node{list}))
+		}
+	}
+	t, err := parse(separateHeadings(b.Bytes()))
+	if err != nil {
+		return t, err
+	}
+	if _, err := getDateHeader(t); err != nil {
+		// rollover needs to know which day the file is for
+		verbosef("%s has no date heading (e.g. '# {{date}}'), so one is added", templateBase)
+		h := headingNode(blackfriday.NewNode(blackfriday.Document), 1, p.date.Format("2006-01-02, Monday"))
+		h.Unlink()
+		if t.node.FirstChild != nil {
+			t.node.FirstChild.InsertBefore(h)
+		} else {
+			t.node.AppendChild(h)
+		}
+	}
+	return t, nil
+}
+
+// renderNodes renders nodes as markdown. They are moved into a new document, with adjacent lists merged,
+// as they would otherwise be read back as one loose list
+func renderNodes(nodes []*blackfriday.Node) string {
+	if len(nodes) == 0 {
+		return ""
+	}
+	doc := blackfriday.NewNode(blackfriday.Document)
+	for _, n := range nodes {
+		if n.Type != blackfriday.List || doc.LastChild == nil || doc.LastChild.Type != blackfriday.List {
+			doc.AppendChild(n)
+			continue
+		}
+		for item := n.FirstChild; item != nil; {
+			next := item.Next
+			doc.LastChild.AppendChild(item)
+			item = next
+		}
+	}
+	var b bytes.Buffer
+	render(markdown.NewRenderer(&markdown.Options{Terminal: false, HashHeaders: true}), &b, doc)
+	return strings.TrimSpace(b.String()) + "\n"
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+func TestExecuteDateHeading(t *testing.T) {
+	date := time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)
+	for _, tc := range []struct {
+		name     string
+		template string
+	}{
+		{"without a date heading", "## Plan for {{weekday}}\n\n{{inbox}}\n## Rolled Over\n\n{{rolled}}"},
+		{"with a date heading", "# {{date}}, {{weekday}}\n\n## Inbox\n\n{{inbox}}"},
+		{"with another level 1 heading", "# My day\n\n## Inbox\n\n{{inbox}}"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := template.New(templateBase).Funcs((&dayParts{}).funcs(nil)).Parse(tc.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &dayParts{date: date, custom: map[string][]*blackfriday.Node{}}
+			out, err := p.execute(tmpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := getDateHeader(out)
+			if err != nil {
+				t.Fatalf("getDateHeader: %v", err)
+			}
+			if !d.Equal(date) {
+				t.Errorf("date heading is %s, expected %s", d.Format("2006-01-02"), date.Format("2006-01-02"))
+			}
+			headings := 0
+			for n := out.node.FirstChild; n != nil; n = n.Next {
+				if n.Type == blackfriday.Heading && n.Level == 1 && checkDateHeading(inlineText(n)) == nil {
+					headings++
+				}
+			}
+			if headings != 1 {
+				t.Errorf("%d date headings, expected 1", headings)
+			}
+		})
+	}
+}
+
+func TestExecuteRecurring(t *testing.T) {
+	date := time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)
+	for _, tc := range []struct {
+		name     string
+		template string
+		section  string
+	}{
+		{"with recurring", "# {{date}}\n\n## Routine\n\n{{recurring}}\n## Inbox\n\n{{inbox}}", "Routine"},
+		{"without recurring", "# {{date}}\n\n## Inbox\n\n{{inbox}}", "Daily"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recurring, err := parse([]byte("# Recurring\n\n## Daily\n\n- [ ] Stretch\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tmpl, err := template.New(templateBase).Funcs((&dayParts{}).funcs(nil)).Parse(tc.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := &dayParts{date: date, recurring: recurring, done: completions{}, custom: map[string][]*blackfriday.Node{}}
+			out, err := p.execute(tmpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := 0
+			for _, task := range out.Tasks() {
+				if task.Description == "Stretch" {
+					found++
+					if task.Section != tc.section {
+						t.Errorf("'Stretch' is in %s, expected %s", task.Section, tc.section)
+					}
+				}
+			}
+			if found != 1 {
+				t.Errorf("'Stretch' appears %d times, expected once", found)
+			}
+		})
+	}
+}
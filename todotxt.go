@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	todoPriority = regexp.MustCompile(`^\(([A-Z])\)$`)
+	// todoRec is the rec: extension, e.g. rec:+1w (on a calendar) or rec:3d (after being done)
+	todoRec = regexp.MustCompile(`^(\+?)(\d+)([dwmy])$`)
+)
+
+// writeTodoTxt writes a line per task (see http://todotxt.org). Statuses other than todo and done are kept as `status:`.
+// Recurring tasks use the rec: extension, when their rule is a simple interval
+func writeTodoTxt(w io.Writer, ts []exportTask) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range ts {
+		var (
+			words = []string{}
+			desc  = plainDescription(t.Description)
+		)
+		if t.Status == "x" {
+			words = append(words, "x")
+			if !t.Completed.IsZero() {
+				words = append(words, t.Completed.Format("2006-01-02"))
+			}
+		}
+		if p := priorityLetter(t.Priority); p != "" {
+			if t.Status == "x" {
+				// priorities are dropped from completed tasks, by convention
+				desc += " pri:" + p
+			} else {
+				words = append(words, "("+p+")")
+			}
+		}
+		// a creation date needs a completion date, on completed tasks
+		if !t.Created.IsZero() && (t.Status != "x" || !t.Completed.IsZero()) {
+			words = append(words, t.Created.Format("2006-01-02"))
+		}
+		words = append(words, desc)
+		if t.Status != " " && t.Status != "x" {
+			words = append(words, "status:"+t.Status)
+		}
+		if !t.Due.IsZero() {
+			words = append(words, "due:"+t.Due.Format("2006-01-02"))
+		}
+		if !t.Scheduled.IsZero() {
+			words = append(words, "t:"+t.Scheduled.Format("2006-01-02"))
+		}
+		if r := t.Recurrence; r != nil {
+			rec, ok := todoTxtRec(r)
+			if !ok {
+				verbosef("skipping '%s', as todo.txt can't express %s", t.Description, r)
+				continue
+			}
+			words = append(words, "rec:"+rec)
+		}
+		if _, err := bw.WriteString(strings.Join(words, " ") + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// todoTxtRec is a rule as a rec: value, if it is a simple interval
+func todoTxtRec(r *recurrence) (string, bool) {
+	if len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 || len(r.ByMonth) > 0 || len(r.Times) > 0 {
+		return "", false
+	}
+	unit, ok := map[recurType]string{daily: "d", weekly: "w", monthly: "m", yearly: "y"}[r.Freq]
+	if !ok {
+		return "", false
+	}
+	rec := strconv.Itoa(r.Interval) + unit
+	if !r.AfterDone {
+		rec = "+" + rec
+	}
+	return rec, true
+}
+
+func readTodoTxt(r io.Reader) ([]task, error) {
+	var (
+		sc  = bufio.NewScanner(r)
+		ret = []task{}
+		n   = 0
+	)
+	for sc.Scan() {
+		n++
+		words := strings.Fields(sc.Text())
+		if len(words) == 0 {
+			continue
+		}
+		t := task{Status: " "}
+		priority := ""
+		if words[0] == "x" {
+			t.Status = "x"
+			words = words[1:]
+			// completion and creation dates
+			for _, d := range []*time.Time{&t.Completed, &t.Created} {
+				if len(words) > 0 && isTodoDate(words[0]) {
+					*d, _ = time.ParseInLocation("2006-01-02", words[0], time.Local)
+					words = words[1:]
+				}
+			}
+		} else {
+			if m := todoPriority.FindStringSubmatch(words[0]); m != nil {
+				priority = m[1]
+				words = words[1:]
+			}
+			if len(words) > 0 && isTodoDate(words[0]) {
+				t.Created, _ = time.ParseInLocation("2006-01-02", words[0], time.Local)
+				words = words[1:]
+			}
+		}
+		desc := []string{}
+		for _, w := range words {
+			kv := strings.SplitN(w, ":", 2)
+			if len(kv) == 2 && kv[1] != "" {
+				var err error
+				switch kv[0] {
+				case "due":
+					t.Due, err = parseDate(kv[1], time.Now())
+				case "t":
+					t.Scheduled, err = parseDate(kv[1], time.Now())
+				case "pri":
+					priority = strings.ToUpper(kv[1])
+				case "status":
+					k, ok := statusKey(kv[1])
+					if !ok {
+						err = fmt.Errorf("unknown status '%s'", kv[1])
+					}
+					t.Status = k
+				case "rec":
+					t.Recurrence, err = parseTodoRec(kv[1])
+				default:
+					desc = append(desc, w)
+					continue
+				}
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", n, err)
+				}
+				continue
+			}
+			switch {
+			case len(w) > 1 && w[0] == '@':
+				t.Contexts = append(t.Contexts, w[1:])
+			case len(w) > 1 && w[0] == '+':
+				t.Projects = append(t.Projects, w[1:])
+			}
+			desc = append(desc, w)
+		}
+		t.Description = strings.Join(desc, " ")
+		t.Priority, _ = parsePriority("(" + priority + ")")
+		if t.Description != "" {
+			ret = append(ret, t)
+		}
+	}
+	return ret, sc.Err()
+}
+
+func isTodoDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+func parseTodoRec(s string) (*recurrence, error) {
+	m := todoRec.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("rec:%s is not an interval such as +1w or 3d", s)
+	}
+	n, _ := strconv.Atoi(m[2])
+	r := &recurrence{Interval: n, AfterDone: m[1] == ""}
+	r.Freq = map[string]recurType{"d": daily, "w": weekly, "m": monthly, "y": yearly}[m[3]]
+	if r.AfterDone && r.Freq == yearly {
+		// after-done counts in days, weeks or months
+		r.Freq, r.Interval = monthly, 12*n
+	}
+	return r, r.validate()
+}
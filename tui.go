@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+	"golang.org/x/term"
+)
+
+const tuiHelp = "j/k move  space status  J/K reorder  </> section  a add  d delete  r rollover  q quit"
+
+// tuiRow is a line in the tui: a section heading, or a task (perhaps nested)
+type tuiRow struct {
+	section string
+	item    *blackfriday.Node // nil for a section
+	depth   int
+}
+
+type tui struct {
+	file   string
+	doc    tasks
+	rows   []tuiRow
+	cursor int
+	offset int // the first row on screen
+	msg    string
+	in     *bufio.Reader
+}
+
+// tuiCmd edits today.md interactively. Every change is written straight back
+func tuiCmd(args []string) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("tui needs a terminal")
+	}
+	file, err := getTodayFilename()
+	if err != nil {
+		return err
+	}
+	u := &tui{file: file, in: bufio.NewReader(os.Stdin)}
+	if err := u.load(); err != nil {
+		return err
+	}
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	// alternate screen, hidden cursor
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		_ = term.Restore(int(os.Stdin.Fd()), state)
+	}()
+	for {
+		u.draw()
+		key, err := u.readKey()
+		if err != nil {
+			return err
+		}
+		if key == "q" || key == "ctrl-c" {
+			return nil
+		}
+		u.msg = ""
+		if err := u.handle(key); err != nil {
+			u.msg = err.Error()
+		}
+	}
+}
+
+func (u *tui) load() error {
+	t, err := parseFile(u.file)
+	if err != nil {
+		return err
+	}
+	u.doc = t
+	u.refresh(nil)
+	return nil
+}
+
+// refresh rebuilds the rows from the document, keeping the cursor on the selected item if it is still there
+func (u *tui) refresh(selected *blackfriday.Node) {
+	u.rows = []tuiRow{}
+	section := ""
+	var addItems func(list *blackfriday.Node, depth int)
+	addItems = func(list *blackfriday.Node, depth int) {
+		for item := list.FirstChild; item != nil; item = item.Next {
+			u.rows = append(u.rows, tuiRow{section: section, item: item, depth: depth})
+			for c := item.FirstChild; c != nil; c = c.Next {
+				if c.Type == blackfriday.List {
+					addItems(c, depth+1)
+				}
+			}
+		}
+	}
+	for n := u.doc.node.FirstChild; n != nil; n = n.Next {
+		switch {
+		case n.Type == blackfriday.Heading && n.Level > 1:
+			section = inlineText(n)
+			u.rows = append(u.rows, tuiRow{section: section})
+		case n.Type == blackfriday.List:
+			addItems(n, 0)
+		}
+	}
+	for i, r := range u.rows {
+		if selected != nil && r.item == selected {
+			u.cursor = i
+		}
+	}
+	if u.cursor >= len(u.rows) {
+		u.cursor = len(u.rows) - 1
+	}
+	if u.cursor < 0 {
+		u.cursor = 0
+	}
+}
+
+func (u *tui) current() tuiRow {
+	if len(u.rows) == 0 {
+		return tuiRow{section: "Inbox"}
+	}
+	return u.rows[u.cursor]
+}
+
+// sections are the sections in the document, in order
+func (u *tui) sections() []string {
+	ret := []string{}
+	for _, r := range u.rows {
+		if r.item == nil {
+			ret = append(ret, r.section)
+		}
+	}
+	return ret
+}
+
+func (u *tui) save(selected *blackfriday.Node) error {
+	u.refresh(selected)
+	return newFile(u.file, u.doc)
+}
+
+func (u *tui) handle(key string) error {
+	row := u.current()
+	switch key {
+	case "j", "down":
+		if u.cursor < len(u.rows)-1 {
+			u.cursor++
+		}
+	case "k", "up":
+		if u.cursor > 0 {
+			u.cursor--
+		}
+	case "g", "home":
+		u.cursor = 0
+	case "G", "end":
+		u.cursor = len(u.rows) - 1
+	case " ", "enter":
+		if row.item == nil {
+			return nil
+		}
+		t, _ := parseTask(row.item, u.doc.ref)
+		setStatus(row.item, nextStatus(t.Status))
+		return u.save(row.item)
+	case "J", "K":
+		if row.item == nil {
+			return nil
+		}
+		if !shiftItem(row.item, key == "K") {
+			return nil
+		}
+		return u.save(row.item)
+	case "<", ">", "H", "L":
+		if row.item == nil {
+			return nil
+		}
+		sections := u.sections()
+		for i, s := range sections {
+			if s != row.section {
+				continue
+			}
+			if key == "<" || key == "H" {
+				i--
+			} else {
+				i++
+			}
+			if i < 0 || i >= len(sections) {
+				return nil
+			}
+			u.doc.moveToSection(row.item, sections[i])
+			u.msg = "moved to " + sections[i]
+			return u.save(row.item)
+		}
+	case "a":
+		text, ok, err := u.prompt("add to " + row.section + ": ")
+		if err != nil || !ok || strings.TrimSpace(text) == "" {
+			return err
+		}
+		item := itemNode(u.doc.SectionList(row.section), "[ ] "+strings.TrimSpace(text))
+		return u.save(item)
+	case "d":
+		if row.item == nil {
+			return nil
+		}
+		answer, ok, err := u.prompt("delete '" + inlineText(row.item.FirstChild) + "'? (y/n) ")
+		if err != nil || !ok || !strings.EqualFold(strings.TrimSpace(answer), "y") {
+			return err
+		}
+		removeItem(row.item)
+		return u.save(nil)
+	case "r":
+		if err := prune([]string{"rollover"}, true, false); err != nil {
+			return err
+		}
+		u.msg = "rolled over"
+		return u.load()
+	}
+	return nil
+}
+
+// readKey reads a key press, naming the special ones
+func (u *tui) readKey() (string, error) {
+	r, _, err := u.in.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch r {
+	case 3:
+		return "ctrl-c", nil
+	case '\r', '\n':
+		return "enter", nil
+	case 127, 8:
+		return "backspace", nil
+	case 27:
+		if u.in.Buffered() == 0 {
+			return "esc", nil
+		}
+		b, _ := u.in.ReadByte()
+		if b != '[' && b != 'O' {
+			return "esc", nil
+		}
+		c, _ := u.in.ReadByte()
+		switch c {
+		case 'A':
+			return "up", nil
+		case 'B':
+			return "down", nil
+		case 'H':
+			return "home", nil
+		case 'F':
+			return "end", nil
+		}
+		return "esc", nil
+	}
+	return string(r), nil
+}
+
+// prompt reads a line on the bottom row. ok is false if it was cancelled with escape
+func (u *tui) prompt(label string) (string, bool, error) {
+	line := []rune{}
+	_, height := u.size()
+	for {
+		fmt.Printf("\x1b[%d;1H\x1b[2K%s%s\x1b[?25h", height, label, string(line))
+		key, err := u.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch key {
+		case "enter":
+			fmt.Print("\x1b[?25l")
+			return string(line), true, nil
+		case "esc", "ctrl-c":
+			fmt.Print("\x1b[?25l")
+			return "", false, nil
+		case "backspace":
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		default:
+			if len([]rune(key)) == 1 {
+				line = append(line, []rune(key)...)
+			}
+		}
+	}
+}
+
+func (u *tui) size() (int, int) {
+	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || h < 4 {
+		return 80, 24
+	}
+	return w, h
+}
+
+func (u *tui) draw() {
+	width, height := u.size()
+	body := height - 3 // title, status and help
+	if u.cursor < u.offset {
+		u.offset = u.cursor
+	}
+	if u.cursor >= u.offset+body {
+		u.offset = u.cursor - body + 1
+	}
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString("\x1b[1m" + truncate(u.doc.GetFirstHeadingText(), width) + "\x1b[0m\r\n")
+	for i := u.offset; i < len(u.rows) && i < u.offset+body; i++ {
+		r := u.rows[i]
+		var line string
+		if r.item == nil {
+			line = "\x1b[1m## " + r.section + "\x1b[0m"
+			if i == u.cursor {
+				line = "\x1b[7m## " + r.section + "\x1b[0m"
+			}
+		} else {
+			text := truncate(strings.Repeat("  ", r.depth+1)+inlineText(r.item.FirstChild), width)
+			if i == u.cursor {
+				text = "\x1b[7m" + text + "\x1b[0m"
+			}
+			line = text
+		}
+		b.WriteString(line + "\r\n")
+	}
+	fmt.Print(b.String())
+	fmt.Printf("\x1b[%d;1H%s\r\n%s", height-1, truncate(u.msg, width), "\x1b[2m"+truncate(tuiHelp, width)+"\x1b[0m")
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/russross/blackfriday/v2"
+)
+
+// watcher keeps today.md tidy while it is being edited, and rolls it over once the day has passed
+type watcher struct {
+	dryRun  bool
+	written map[string][]byte // what was last written to each file, so that our own writes are not checked again
+	lastErr string
+	tried   time.Time // the day of the last rollover, which is not retried if it fails
+}
+
+func watch(args []string) error {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	dryRun := fs.Bool("dryrun", false, "report problems, without fixing them or rolling over")
+	settle := fs.Duration("settle", 500*time.Millisecond, "wait for saves to settle for this long before checking")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	base, err := getBaseDir()
+	if err != nil {
+		return err
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+	// the directory rather than the files, as editors often replace the file when saving
+	if err := fw.Add(base); err != nil {
+		return err
+	}
+	w := &watcher{dryRun: *dryRun, written: map[string][]byte{}}
+	w.report("watching %s", base)
+	w.checkDay()
+
+	var (
+		pending = map[string]bool{}
+		timer   = time.NewTimer(*settle)
+		ticker  = time.NewTicker(time.Minute)
+	)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev, ok := <-fw.Events:
+			if !ok {
+				return nil
+			}
+			switch filepath.Base(ev.Name) {
+			case todayBase, recurringBase, laterBase:
+			default:
+				continue
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				pending[ev.Name] = true
+				timer.Reset(*settle)
+			}
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return nil
+			}
+			w.report("watch error: %v", err)
+		case <-timer.C:
+			for f := range pending {
+				w.check(f)
+			}
+			pending = map[string]bool{}
+		case <-ticker.C:
+			w.checkDay()
+		}
+	}
+}
+
+// report always goes to stderr, with the time
+func (w *watcher) report(format string, args ...interface{}) {
+	logger.Printf(time.Now().Format("15:04:05")+" "+format, args...)
+}
+
+// fail reports an error, unless it is the same as the last one
+func (w *watcher) fail(err error) {
+	if err.Error() != w.lastErr {
+		w.report("%v", err)
+	}
+	w.lastErr = err.Error()
+}
+
+// checkDay rolls over once today.md is from a previous day
+func (w *watcher) checkDay() {
+	old, err := loadToday()
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	d, err := getDateHeader(old)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	w.lastErr = ""
+	if !day(time.Now()).After(d) {
+		return
+	}
+	if w.tried.Equal(d) {
+		return
+	}
+	if w.dryRun {
+		w.report("%s is from %s, and would be rolled over", todayBase, d.Format("2006-01-02"))
+		w.tried = d
+		return
+	}
+	w.tried = d
+	if err := prune([]string{"rollover"}, true, false); err != nil {
+		w.fail(err)
+		return
+	}
+	w.report("rolled over %s from %s", todayBase, d.Format("2006-01-02"))
+	w.remember(todayBase)
+	w.remember(laterBase)
+}
+
+// remember what a file holds now, after writing it
+func (w *watcher) remember(name string) {
+	base, err := getBaseDir()
+	if err != nil {
+		return
+	}
+	f := filepath.Join(base, name)
+	if b, err := ioutil.ReadFile(f); err == nil {
+		w.written[f] = b
+	}
+}
+
+// check lints a file which was saved, and repairs it and normalises its dates unless this is a dry run
+func (w *watcher) check(file string) {
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	if bytes.Equal(b, w.written[file]) {
+		return
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	name := filepath.Base(file)
+	problems := lintFile(name, b, cfg)
+	fixable := false
+	for _, p := range problems {
+		if !w.dryRun && p.fixable {
+			w.report("fixed: %s", p)
+			fixable = true
+			continue
+		}
+		w.report("%s", p)
+	}
+	if w.dryRun {
+		return
+	}
+	t, err := parse(separateHeadings(b))
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	if fixable {
+		t = lintFix(file, t, cfg)
+	}
+	normalised := false
+	if name != recurringBase {
+		ref, ok := t.headingDate()
+		if !ok {
+			ref = day(time.Now())
+		}
+		normalised = normaliseAll(t, ref)
+	}
+	if !fixable && !normalised {
+		return
+	}
+	if err := newFile(file, t); err != nil {
+		w.fail(err)
+		return
+	}
+	if normalised {
+		w.report("%s: normalised relative dates", name)
+	}
+	w.remember(name)
+}
+
+// normaliseAll rewrites the relative dates of every item, reporting whether there were any
+func normaliseAll(t tasks, ref time.Time) bool {
+	changed := false
+	t.node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && n.Type == blackfriday.Item && n.FirstChild != nil {
+			before := inlineText(n.FirstChild)
+			normaliseDates(n, ref)
+			changed = changed || inlineText(n.FirstChild) != before
+		}
+		return blackfriday.GoToNext
+	})
+	return changed
+}